@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"cqrs-sample/internal/database"
+	"cqrs-sample/internal/logging"
+	"cqrs-sample/internal/metrics"
 	"cqrs-sample/internal/queue"
 	"cqrs-sample/internal/server"
 	"cqrs-sample/pkg/command"
@@ -10,23 +12,31 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	_ "github.com/joho/godotenv/autoload"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"log"
+	"log/slog"
 	"os"
 )
 
 func main() {
+	slog.SetDefault(logging.New(os.Stdout, logging.ParseLevel(os.Getenv("LOG_LEVEL"))))
+
 	ctx := context.Background()
 	postgresDSN := os.Getenv("POSTGRES_DSN")
 	amqpDial := os.Getenv("AMQP_DIAL")
 
-	db, err := gorm.Open(postgres.Open(postgresDSN), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(postgresDSN), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatalln(err)
 	}
 
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		log.Fatalln(err)
+	}
+
 	postgresDB := database.NewGorm(db)
 
 	amqpConnection, err := amqp.Dial(amqpDial)
@@ -43,25 +53,72 @@ func main() {
 	}()
 
 	libraryExchange := os.Getenv("LIBRARY_EXCHANGE")
-	rabbitMQPublisher := queue.NewRabbitMQPublisher(channel, libraryExchange)
+	rabbitMQPublisher := metrics.NewInstrumentedPublisher(queue.NewRabbitMQPublisher(channel, libraryExchange))
 
 	subscribeArtistCommand := command.NewSubscribeArtist(postgresDB, rabbitMQPublisher)
-	publishAlbumCommand := command.NewPublishAlbum(postgresDB, rabbitMQPublisher)
+	mergeArtistsCommand := command.NewMergeArtists(postgresDB, rabbitMQPublisher)
+	createAlbumCommand := command.NewCreateAlbum(postgresDB, rabbitMQPublisher)
+	scheduleAlbumCommand := command.NewScheduleAlbum(postgresDB, rabbitMQPublisher)
 	publishSongCommand := command.NewPublishSong(postgresDB, rabbitMQPublisher)
-	playSongCommand := command.NewPlaySong(rabbitMQPublisher)
+	playSongCommand := command.NewPlaySong(postgresDB, rabbitMQPublisher)
+	registerListenerCommand := command.NewRegisterListener(postgresDB, rabbitMQPublisher)
+	followArtistCommand := command.NewFollowArtist(postgresDB, rabbitMQPublisher)
+	likeSongCommand := command.NewLikeSong(postgresDB, rabbitMQPublisher)
+	rateAlbumCommand := command.NewRateAlbum(postgresDB, rabbitMQPublisher)
+	createPlaylistCommand := command.NewCreatePlaylist(postgresDB, rabbitMQPublisher)
+	renamePlaylistCommand := command.NewRenamePlaylist(postgresDB, rabbitMQPublisher)
+	addSongToPlaylistCommand := command.NewAddSongToPlaylist(postgresDB, rabbitMQPublisher)
+	removeSongFromPlaylistCommand := command.NewRemoveSongFromPlaylist(postgresDB, rabbitMQPublisher)
+	reorderPlaylistCommand := command.NewReorderPlaylist(postgresDB, rabbitMQPublisher)
+	createImportCommand := command.NewCreateImport(postgresDB)
+	runImportCommand := command.NewRunImport(postgresDB, rabbitMQPublisher)
+	getImportCommand := command.NewGetImport(postgresDB)
 
-	artistHandler := handler.NewArtistWriter(subscribeArtistCommand)
-	albumHandler := handler.NewAlbumWriter(publishAlbumCommand)
+	artistHandler := handler.NewArtistWriter(subscribeArtistCommand, mergeArtistsCommand)
+	albumHandler := handler.NewAlbumWriter(createAlbumCommand, scheduleAlbumCommand)
 	songHandler := handler.NewSongWriter(publishSongCommand, playSongCommand)
+	listenerHandler := handler.NewListenerWriter(
+		registerListenerCommand,
+		followArtistCommand,
+		likeSongCommand,
+		rateAlbumCommand,
+	)
+	playlistHandler := handler.NewPlaylistWriter(
+		createPlaylistCommand,
+		renamePlaylistCommand,
+		addSongToPlaylistCommand,
+		removeSongFromPlaylistCommand,
+		reorderPlaylistCommand,
+	)
+	importHandler := handler.NewImportWriter(createImportCommand, runImportCommand, getImportCommand)
 
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(logging.Requests)
+	r.Use(metrics.Requests)
 	r.Use(middleware.Recoverer)
+	r.Handle("/metrics", promhttp.Handler())
 	r.Post("/artists", artistHandler.Create)
+	r.Post("/artists/merge", artistHandler.Merge)
 	r.Post("/albums", albumHandler.Create)
+	r.Post("/albums/{albumID}/schedule", albumHandler.Schedule)
 	r.Post("/songs", songHandler.Create)
 	r.Post("/player", songHandler.Play)
+	r.Post("/listeners", listenerHandler.Create)
+	r.Post("/listeners/{listenerID}/follows", listenerHandler.Follow)
+	r.Post("/listeners/{listenerID}/likes", listenerHandler.Like)
+	r.Post("/listeners/{listenerID}/ratings", listenerHandler.Rate)
+	r.Post("/playlists", playlistHandler.Create)
+	r.Patch("/playlists/{playlistID}", playlistHandler.Rename)
+	r.Post("/playlists/{playlistID}/songs", playlistHandler.AddSong)
+	r.Put("/playlists/{playlistID}/songs", playlistHandler.Reorder)
+	r.Delete("/playlists/{playlistID}/songs/{songID}", playlistHandler.RemoveSong)
+	r.Post("/imports", importHandler.Create)
+	r.Get("/imports/{importID}", importHandler.Get)
+	r.Post("/imports/{importID}/resume", importHandler.Resume)
 
 	s := server.New(r)
+	s.OnShutdown(importHandler.Shutdown)
 	if err := s.StartWithGracefulShutdown(ctx, ":3030"); err != nil {
 		log.Fatalln(err)
 	}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"cqrs-sample/internal/database"
+	"cqrs-sample/internal/logging"
+	"cqrs-sample/pkg/query"
+	"flag"
+	_ "github.com/joho/godotenv/autoload"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	slog.SetDefault(logging.New(os.Stderr, logging.ParseLevel(os.Getenv("LOG_LEVEL"))))
+
+	format := flag.String("format", query.JSONLFormat, "output format, jsonl or csv")
+	output := flag.String("output", "", "file to write to (defaults to stdout)")
+	flag.Parse()
+
+	if err := query.ValidateExportFormat(*format); err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mongoURI := os.Getenv("MONGO_URI")
+	mongoClient, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	defer func() {
+		if err := mongoClient.Disconnect(context.Background()); err != nil {
+			slog.Error("mongo disconnect failed", "error", err)
+		}
+	}()
+
+	libraryDatabase := os.Getenv("LIBRARY_DATABASE")
+	mongoDB, err := database.NewMongo(mongoClient.Database(libraryDatabase))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		defer func() {
+			if err := file.Close(); err != nil {
+				slog.Error("close output failed", "error", err)
+			}
+		}()
+
+		w = file
+	}
+
+	if err := query.NewExportCatalog(mongoDB).Execute(ctx, *format, w); err != nil {
+		slog.Error("export failed", "error", err)
+		stop()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"cqrs-sample/internal/database"
+	"cqrs-sample/internal/logging"
+	"cqrs-sample/internal/queue"
+	"cqrs-sample/pkg/command"
+	"cqrs-sample/pkg/song"
+	"flag"
+	"fmt"
+	_ "github.com/joho/godotenv/autoload"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+func main() {
+	slog.SetDefault(logging.New(os.Stderr, logging.ParseLevel(os.Getenv("LOG_LEVEL"))))
+
+	format := flag.String("format", "", "input format, csv or jsonl (defaults to the file extension)")
+	resume := flag.String("resume", "", "id of an interrupted import to resume instead of reading a file")
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-format csv|jsonl] FILE\n       %s -resume IMPORT_ID\n", os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *resume == "" && flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	postgresDSN := os.Getenv("POSTGRES_DSN")
+	amqpDial := os.Getenv("AMQP_DIAL")
+
+	db, err := gorm.Open(postgres.Open(postgresDSN), &gorm.Config{TranslateError: true})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	postgresDB := database.NewGorm(db)
+
+	amqpConnection, err := amqp.Dial(amqpDial)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer func() {
+		_ = amqpConnection.Close()
+	}()
+
+	channel, err := amqpConnection.Channel()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer func() {
+		_ = channel.Close()
+	}()
+
+	libraryExchange := os.Getenv("LIBRARY_EXCHANGE")
+	// the import exits once done, before any scrape could collect metrics
+	rabbitMQPublisher := queue.NewRabbitMQPublisher(channel, libraryExchange)
+
+	jobID := *resume
+	if jobID == "" {
+		path := flag.Arg(0)
+		if *format == "" {
+			*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		job, err := command.NewCreateImport(postgresDB).Execute(ctx, command.CreateImportCommand{
+			Format: *format,
+			Data:   file,
+		})
+		_ = file.Close()
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		jobID = job.ID
+		fmt.Printf("import %s: %d rows\n", job.ID, len(job.Rows))
+	}
+
+	job, err := command.NewRunImport(postgresDB, rabbitMQPublisher).Execute(ctx, jobID)
+	for _, row := range job.Rows {
+		if row.Status == song.RowFailed {
+			fmt.Printf("line %d: %s: %s\n", row.Line, row.Record.Key(), row.Error)
+		}
+	}
+
+	fmt.Printf("import %s %s: %d succeeded, %d failed, %d pending\n",
+		job.ID,
+		job.Status,
+		job.Count(song.RowSucceeded),
+		job.Count(song.RowFailed),
+		job.Count(song.RowPending),
+	)
+
+	if err != nil {
+		log.Fatalf("import interrupted, resume with -resume %s: %v", jobID, err)
+	}
+
+	if job.Count(song.RowFailed) > 0 {
+		os.Exit(1)
+	}
+}
@@ -2,24 +2,40 @@ package main
 
 import (
 	"context"
+	"cqrs-sample/internal/cache"
 	"cqrs-sample/internal/database"
+	"cqrs-sample/internal/logging"
+	"cqrs-sample/internal/metrics"
+	"cqrs-sample/internal/queue"
 	"cqrs-sample/internal/server"
+	"cqrs-sample/pkg/event"
 	"cqrs-sample/pkg/handler"
 	"cqrs-sample/pkg/query"
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	_ "github.com/joho/godotenv/autoload"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
+	"log/slog"
 	"os"
+	"strconv"
+	"time"
 )
 
+// defaultCacheTTL bounds how stale cached play counts get, since plays do
+// not invalidate the entries they change.
+const defaultCacheTTL = 5 * time.Minute
+
 func main() {
+	slog.SetDefault(logging.New(os.Stdout, logging.ParseLevel(os.Getenv("LOG_LEVEL"))))
+
 	ctx := context.Background()
 	mongoURI := os.Getenv("MONGO_URI")
 
-	mongoClient, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	mongoClient, err := mongo.Connect(ctx, options.Client().
+		ApplyURI(mongoURI).
+		SetMonitor(metrics.MongoMonitor()))
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -35,21 +51,87 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	getArtistQuery := query.NewGetArtist(mongoDB)
-	getAlbumQuery := query.NewGetAlbum(mongoDB)
-	getAlbumsByArtistQuery := query.NewGetAlbumsByArtist(mongoDB)
-	getSongQuery := query.NewGetSong(mongoDB)
+	cacheTTL, _ := time.ParseDuration(os.Getenv("CACHE_TTL"))
+	if cacheTTL <= 0 {
+		cacheTTL = defaultCacheTTL
+	}
+
+	var store cache.Store
+	if redisAddr := os.Getenv("CACHE_REDIS_ADDR"); redisAddr != "" {
+		redisClient := redis.NewClient(&redis.Options{Addr: redisAddr})
+		defer func() {
+			_ = redisClient.Close()
+		}()
+
+		store = cache.NewRedis(redisClient, os.Getenv("CACHE_PREFIX"), cacheTTL)
+	} else {
+		cacheSize, _ := strconv.Atoi(os.Getenv("CACHE_SIZE"))
+		lru := cache.NewLRU(cacheSize, cacheTTL)
+		store = lru
+
+		amqpConnection, err := amqp.Dial(os.Getenv("AMQP_DIAL"))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer func() {
+			_ = amqpConnection.Close()
+		}()
+
+		subscriber := queue.NewRabbitMQSubscriber(amqpConnection)
+		cacheInvalidatedHandler := queue.Chain(
+			queue.Logger(slog.Default()),
+			queue.Recoverer,
+		).Handler(handler.NewCacheInvalidated(lru))
+		go func() {
+			exchange := os.Getenv("LIBRARY_EXCHANGE")
+			if err := subscriber.Broadcast(ctx, exchange, event.CacheInvalidatedEvent, cacheInvalidatedHandler); err != nil {
+				log.Fatalln(err)
+			}
+		}()
+	}
+
+	cachedDB := cache.NewLibrary(mongoDB, store)
+
+	getArtistQuery := query.NewGetArtist(cachedDB)
+	getDuplicateArtistsQuery := query.NewGetDuplicateArtists(mongoDB)
+	getAlbumQuery := query.NewGetAlbum(cachedDB)
+	getAlbumsByArtistQuery := query.NewGetAlbumsByArtist(cachedDB)
+	getSongQuery := query.NewGetSong(cachedDB)
+	getListeningHistoryQuery := query.NewGetListeningHistory(mongoDB)
+	getPlaylistQuery := query.NewGetPlaylist(mongoDB)
+	getFeedQuery := query.NewGetFeed(mongoDB)
+	getLikesQuery := query.NewGetLikes(mongoDB)
+	getAlbumsByGenreQuery := query.NewGetAlbumsByGenre(mongoDB)
+	getArtistsByGenreQuery := query.NewGetArtistsByGenre(mongoDB)
+	exportCatalogQuery := query.NewExportCatalog(mongoDB)
+	waitForProjectionQuery := query.NewWaitForProjection(mongoDB)
+	stuckAfter, _ := time.ParseDuration(os.Getenv("PROJECTION_STUCK_AFTER"))
+	getProjectionsQuery := query.NewGetProjections(mongoDB, stuckAfter)
 
-	artistHandler := handler.NewArtistReader(getArtistQuery, getAlbumsByArtistQuery)
+	artistHandler := handler.NewArtistReader(getArtistQuery, getAlbumsByArtistQuery, getDuplicateArtistsQuery)
 	albumHandler := handler.NewAlbumReader(getAlbumQuery)
 	songHandler := handler.NewSongReader(getSongQuery)
+	listenerHandler := handler.NewListenerReader(getListeningHistoryQuery, getFeedQuery, getLikesQuery)
+	playlistHandler := handler.NewPlaylistReader(getPlaylistQuery)
+	genreHandler := handler.NewGenreReader(getAlbumsByGenreQuery, getArtistsByGenreQuery)
+	exportHandler := handler.NewExportReader(exportCatalogQuery)
+	projectionHandler := handler.NewProjectionReader(getProjectionsQuery)
 
-	r := chi.NewRouter()
-	r.Use(middleware.Recoverer)
-	r.Get("/artist/{artistID}", artistHandler.Get)
-	r.Get("/artist/{artistID}/albums", artistHandler.GetAlbums)
-	r.Get("/album/{albumID}", albumHandler.Get)
-	r.Get("/song/{songID}", songHandler.Get)
+	consistencyTimeout, _ := time.ParseDuration(os.Getenv("CONSISTENCY_TIMEOUT"))
+	consistencyRetryAfter, _ := time.ParseDuration(os.Getenv("CONSISTENCY_RETRY_AFTER"))
+	r := newRouter(readers{
+		artist:                artistHandler,
+		album:                 albumHandler,
+		song:                  songHandler,
+		listener:              listenerHandler,
+		playlist:              playlistHandler,
+		genre:                 genreHandler,
+		export:                exportHandler,
+		projection:            projectionHandler,
+		waitForProjection:     waitForProjectionQuery,
+		consistencyTimeout:    consistencyTimeout,
+		consistencyRetryAfter: consistencyRetryAfter,
+	})
 
 	s := server.New(r)
 	if err := s.StartWithGracefulShutdown(ctx, ":3031"); err != nil {
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"cqrs-sample/internal/logging"
+	"cqrs-sample/internal/metrics"
+	"cqrs-sample/pkg/handler"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"os"
+	"time"
+)
+
+type (
+	readers struct {
+		artist                *handler.ArtistReader
+		album                 *handler.AlbumReader
+		song                  *handler.SongReader
+		listener              *handler.ListenerReader
+		playlist              *handler.PlaylistReader
+		genre                 *handler.GenreReader
+		export                *handler.ExportReader
+		projection            *handler.ProjectionReader
+		waitForProjection     handler.WaitForProjectionQuery
+		consistencyTimeout    time.Duration
+		consistencyRetryAfter time.Duration
+	}
+)
+
+// newRouter declares every middleware before the first route, as chi
+// panics on a Use that follows one.
+func newRouter(h readers) http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(logging.Requests)
+	r.Use(metrics.Requests)
+	r.Use(middleware.Recoverer)
+	r.Use(handler.ReadYourWrites(h.waitForProjection, h.consistencyTimeout, h.consistencyRetryAfter))
+
+	r.Handle("/metrics", promhttp.Handler())
+	r.With(handler.CacheControl(os.Getenv("ARTIST_CACHE_CONTROL"))).Get("/artist/{artistID}", h.artist.Get)
+	r.Get("/artists/duplicates", h.artist.GetDuplicates)
+	r.Get("/artist/{artistID}/albums", h.artist.GetAlbums)
+	r.With(handler.CacheControl(os.Getenv("ALBUM_CACHE_CONTROL"))).Get("/album/{albumID}", h.album.Get)
+	r.With(handler.CacheControl(os.Getenv("SONG_CACHE_CONTROL"))).Get("/song/{songID}", h.song.Get)
+	r.Get("/listeners/{listenerID}/history", h.listener.History)
+	r.Get("/listeners/{listenerID}/feed", h.listener.Feed)
+	r.Get("/listeners/{listenerID}/likes", h.listener.Likes)
+	r.Get("/playlist/{playlistID}", h.playlist.Get)
+	r.Get("/genres/{genre}/albums", h.genre.GetAlbums)
+	r.Get("/genres/{genre}/artists", h.genre.GetArtists)
+	r.Get("/exports/catalog", h.export.Catalog)
+	r.Get("/admin/projections", h.projection.List)
+
+	return r
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"cqrs-sample/pkg/handler"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Query_Router_Serves_Metrics(t *testing.T) {
+	// Arrange
+	r := newRouter(readers{
+		artist:     handler.NewArtistReader(nil, nil, nil),
+		album:      handler.NewAlbumReader(nil),
+		song:       handler.NewSongReader(nil),
+		listener:   handler.NewListenerReader(nil, nil, nil),
+		playlist:   handler.NewPlaylistReader(nil),
+		genre:      handler.NewGenreReader(nil, nil),
+		export:     handler.NewExportReader(nil),
+		projection: handler.NewProjectionReader(nil),
+	})
+	w := httptest.NewRecorder()
+
+	// Act
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// Assert
+	if w.Code != http.StatusOK {
+		t.Errorf("status: got = %d, want = %d", w.Code, http.StatusOK)
+	}
+}
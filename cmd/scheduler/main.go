@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"cqrs-sample/internal/database"
+	"cqrs-sample/internal/logging"
+	"cqrs-sample/internal/metrics"
+	"cqrs-sample/internal/queue"
+	"cqrs-sample/internal/server"
+	"cqrs-sample/pkg/command"
+	_ "github.com/joho/godotenv/autoload"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultInterval    = time.Minute
+	defaultMetricsAddr = ":3033"
+)
+
+func main() {
+	slog.SetDefault(logging.New(os.Stdout, logging.ParseLevel(os.Getenv("LOG_LEVEL"))))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	postgresDSN := os.Getenv("POSTGRES_DSN")
+	amqpDial := os.Getenv("AMQP_DIAL")
+
+	db, err := gorm.Open(postgres.Open(postgresDSN), &gorm.Config{TranslateError: true})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		log.Fatalln(err)
+	}
+
+	postgresDB := database.NewGorm(db)
+
+	amqpConnection, err := amqp.Dial(amqpDial)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer func() {
+		_ = amqpConnection.Close()
+	}()
+
+	channel, err := amqpConnection.Channel()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer func() {
+		_ = channel.Close()
+	}()
+
+	libraryExchange := os.Getenv("LIBRARY_EXCHANGE")
+	rabbitMQPublisher := metrics.NewInstrumentedPublisher(queue.NewRabbitMQPublisher(channel, libraryExchange))
+	releaseDueAlbumsCommand := command.NewReleaseDueAlbums(postgresDB, rabbitMQPublisher)
+
+	interval := defaultInterval
+	if v := os.Getenv("SCHEDULER_INTERVAL"); v != "" {
+		if interval, err = time.ParseDuration(v); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	metricsAddr := os.Getenv("SCHEDULER_METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = defaultMetricsAddr
+	}
+
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", promhttp.Handler())
+	metricsServer := server.New(metricsMux)
+	go func() {
+		if err := metricsServer.StartWithGracefulShutdown(ctx, metricsAddr); err != nil {
+			slog.ErrorContext(ctx, "metrics server stopped", "error", err)
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	slog.InfoContext(ctx, "releasing due albums", "interval", interval)
+	for {
+		released, err := releaseDueAlbumsCommand.Execute(ctx, time.Now().UTC())
+		if err != nil {
+			slog.ErrorContext(ctx, "release due albums failed", "error", err)
+		}
+
+		for _, album := range released {
+			slog.InfoContext(ctx, "album released", "album_id", album.ID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -2,31 +2,61 @@ package main
 
 import (
 	"context"
+	"cqrs-sample/internal/cache"
 	"cqrs-sample/internal/database"
+	"cqrs-sample/internal/logging"
+	"cqrs-sample/internal/metrics"
 	"cqrs-sample/internal/queue"
+	"cqrs-sample/internal/server"
+	"cqrs-sample/pkg/event"
 	"cqrs-sample/pkg/handler"
-	"fmt"
 	_ "github.com/joho/godotenv/autoload"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultSampleInterval  = 15 * time.Second
+	defaultShutdownTimeout = 30 * time.Second
+	defaultMetricsAddr     = ":3032"
 )
 
 func main() {
-	ctx := context.Background()
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	slog.SetDefault(logging.New(os.Stdout, logging.ParseLevel(os.Getenv("LOG_LEVEL"))))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	mongoURI := os.Getenv("MONGO_URI")
 	amqpDial := os.Getenv("AMQP_DIAL")
 
 	mongoClient, err := mongo.Connect(ctx, options.Client().
-		ApplyURI(mongoURI))
+		ApplyURI(mongoURI).
+		SetMonitor(metrics.MongoMonitor()))
 	if err != nil {
 		log.Fatalln(err)
 	}
 
 	defer func() {
-		if err := mongoClient.Disconnect(ctx); err != nil {
+		if err := mongoClient.Disconnect(context.Background()); err != nil {
 			log.Fatalln(err)
 		}
 	}()
@@ -44,42 +74,199 @@ func main() {
 	defer func() {
 		_ = amqpConnection.Close()
 	}()
+	connectionClosed := amqpConnection.NotifyClose(make(chan *amqp.Error, 1))
 
-	subscriber := queue.NewRabbitMQSubscriber(amqpConnection)
-	artistSubscribedHandler := handler.NewArtistSubscribed(mongoDB)
-	albumPublishedHandler := handler.NewAlbumPublished(mongoDB)
-	songPublishedHandler := handler.NewSongPublished(mongoDB)
-	songPlayedHandler := handler.NewIncrementSongPlays(mongoDB)
-
-	go func() {
-		artistSubscribedQueue := os.Getenv("ARTIST_SUBSCRIBED_QUEUE")
-		if err := subscriber.Subscribe(ctx, artistSubscribedQueue, artistSubscribedHandler); err != nil {
+	exchange := os.Getenv("LIBRARY_EXCHANGE")
+	var invalidator cache.Deleter
+	if redisAddr := os.Getenv("CACHE_REDIS_ADDR"); redisAddr != "" {
+		redisClient := redis.NewClient(&redis.Options{Addr: redisAddr})
+		defer func() {
+			_ = redisClient.Close()
+		}()
+
+		invalidator = cache.NewRedis(redisClient, os.Getenv("CACHE_PREFIX"), 0)
+	} else {
+		amqpChannel, err := amqpConnection.Channel()
+		if err != nil {
 			log.Fatalln(err)
 		}
-	}()
+		defer func() {
+			_ = amqpChannel.Close()
+		}()
+
+		publisher := metrics.NewInstrumentedPublisher(queue.NewRabbitMQPublisher(amqpChannel, exchange))
+		invalidator = cache.NewBroadcast(publisher)
+	}
+
+	projectionDB := cache.NewInvalidating(mongoDB, invalidator)
 
-	go func() {
-		albumPublishedQueue := os.Getenv("ALBUM_PUBLISHED_QUEUE")
-		if err := subscriber.Subscribe(ctx, albumPublishedQueue, albumPublishedHandler); err != nil {
+	// a plays batch fills up only with as many messages in flight as it holds
+	playsBatchSize := intEnv("PLAYS_BATCH_SIZE")
+	if playsBatchSize <= 0 {
+		playsBatchSize = handler.DefaultPlaysBatchSize
+	}
+
+	playsOptions := queueOptions("SONG_PLAYED_QUEUE")
+	if playsOptions.Concurrency <= 0 {
+		playsOptions.Concurrency = playsBatchSize
+	}
+
+	if playsOptions.Prefetch <= 0 {
+		playsOptions.Prefetch = 2 * playsOptions.Concurrency
+	}
+
+	registry := handler.NewRegistry(handler.Options{
+		Prefetch:    intEnv("WORKER_PREFETCH"),
+		Concurrency: intEnv("WORKER_CONCURRENCY"),
+		Timeout:     durationEnv("WORKER_HANDLER_TIMEOUT", 0),
+		Retry: handler.RetryPolicy{
+			Attempts: intEnv("WORKER_RETRY_ATTEMPTS"),
+			Backoff:  durationEnv("WORKER_RETRY_BACKOFF", 0),
+		},
+	})
+	registry.Register(os.Getenv("ARTIST_SUBSCRIBED_QUEUE"), handler.NewArtistSubscribed(projectionDB),
+		queueOptions("ARTIST_SUBSCRIBED_QUEUE"), event.ArtistSubscribedEvent)
+	registry.Register(os.Getenv("ARTIST_MERGED_QUEUE"), handler.NewArtistMerged(projectionDB),
+		queueOptions("ARTIST_MERGED_QUEUE"), event.ArtistMergedEvent)
+	registry.Register(os.Getenv("ALBUM_CHANGED_QUEUE"), handler.NewAlbumChanged(projectionDB),
+		queueOptions("ALBUM_CHANGED_QUEUE"), event.AlbumCreatedEvent, event.AlbumScheduledEvent)
+	registry.Register(os.Getenv("ALBUM_PUBLISHED_QUEUE"), handler.NewAlbumPublished(projectionDB),
+		queueOptions("ALBUM_PUBLISHED_QUEUE"), event.AlbumPublishedEvent)
+	registry.Register(os.Getenv("SONG_PUBLISHED_QUEUE"), handler.NewSongPublished(projectionDB),
+		queueOptions("SONG_PUBLISHED_QUEUE"), event.SongPublishedEvent)
+	registry.Register(os.Getenv("SONG_PLAYED_QUEUE"),
+		handler.NewBatchSongPlays(projectionDB, durationEnv("PLAYS_BATCH_WINDOW", 0), playsBatchSize),
+		playsOptions, event.SongPlayedEvent)
+	registry.Register(os.Getenv("LISTENER_REGISTERED_QUEUE"), handler.NewListenerRegistered(projectionDB),
+		queueOptions("LISTENER_REGISTERED_QUEUE"), event.ListenerRegisteredEvent)
+	registry.Register(os.Getenv("LISTENING_HISTORY_QUEUE"), handler.NewRecordListening(projectionDB),
+		queueOptions("LISTENING_HISTORY_QUEUE"), event.SongPlayedEvent)
+	registry.Register(os.Getenv("PLAYLIST_CHANGED_QUEUE"), handler.NewPlaylistChanged(projectionDB),
+		queueOptions("PLAYLIST_CHANGED_QUEUE"), event.PlaylistCreatedEvent, event.PlaylistRenamedEvent,
+		event.PlaylistSongAddedEvent, event.PlaylistSongRemovedEvent, event.PlaylistReorderedEvent)
+	registry.Register(os.Getenv("ARTIST_FOLLOWED_QUEUE"), handler.NewArtistFollowed(projectionDB),
+		queueOptions("ARTIST_FOLLOWED_QUEUE"), event.ArtistFollowedEvent)
+	registry.Register(os.Getenv("SONG_LIKED_QUEUE"), handler.NewSongLiked(projectionDB),
+		queueOptions("SONG_LIKED_QUEUE"), event.SongLikedEvent)
+	registry.Register(os.Getenv("ALBUM_RATED_QUEUE"), handler.NewAlbumRated(projectionDB),
+		queueOptions("ALBUM_RATED_QUEUE"), event.AlbumRatedEvent)
+
+	subscriber := queue.NewRabbitMQSubscriber(amqpConnection)
+	parking := handler.NewParking(mongoDB)
+	registrations := registry.Registrations()
+	projections := make([]string, len(registrations), len(registrations))
+	checkpoints := make([]*handler.Checkpointed, len(registrations), len(registrations))
+	handlers := make([]queue.Handler, len(registrations), len(registrations))
+	for i, registration := range registrations {
+		if err := subscriber.Declare(ctx, exchange, registration.Queue, registration.Events...); err != nil {
 			log.Fatalln(err)
 		}
-	}()
 
-	go func() {
-		songPublishedQueue := os.Getenv("SONG_PUBLISHED_QUEUE")
-		if err := subscriber.Subscribe(ctx, songPublishedQueue, songPublishedHandler); err != nil {
-			log.Fatalln(err)
+		projections[i] = registration.Queue
+		parked := parking.Wrap(registration.Queue, registration.Handler)
+		retrying := handler.NewRetrying(queue.Timeout(registration.Timeout)(parked), registration.Retry)
+		checkpoints[i] = handler.NewCheckpointed(registration.Queue, retrying, mongoDB)
+		handlers[i] = queue.Chain(
+			queue.Logger(slog.Default()),
+			queue.Metrics(metrics.HandlerRecorder{}),
+			queue.Recoverer,
+		).Handler(checkpoints[i])
+	}
+
+	supervisor := queue.NewSupervisor(durationEnv("WORKER_RESTART_BACKOFF", 0), durationEnv("WORKER_RESTART_MAX_BACKOFF", 0))
+	for i, registration := range registrations {
+		name, h := projections[i], handlers[i]
+		cfg := queue.ConsumerConfig{
+			Prefetch:    registration.Prefetch,
+			Concurrency: registration.Concurrency,
+			Ordered:     !registration.Unordered,
 		}
-	}()
+		supervisor.Go(ctx, name, func(ctx context.Context) error {
+			return subscriber.SubscribeConcurrent(ctx, name, cfg, h)
+		})
+	}
 
-	go func() {
-		songPlayedQueue := os.Getenv("SONG_PLAYED_QUEUE")
-		if err := subscriber.Subscribe(ctx, songPlayedQueue, songPlayedHandler); err != nil {
-			log.Fatalln(err)
+	sampleInterval := durationEnv("PROJECTION_SAMPLE_INTERVAL", defaultSampleInterval)
+	sampleBacklogHandler := handler.NewSampleBacklog(subscriber, metrics.NewInstrumentedBacklog(mongoDB), projections...)
+	supervisor.Go(ctx, "backlog sampler", func(ctx context.Context) error {
+		ticker := time.NewTicker(sampleInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := sampleBacklogHandler.Execute(ctx); err != nil {
+				slog.ErrorContext(ctx, "sample backlog failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
 		}
-	}()
+	})
+
+	metricsAddr := os.Getenv("WORKER_METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = defaultMetricsAddr
+	}
+
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", promhttp.Handler())
+	metricsServer := server.New(metricsMux)
+	supervisor.Go(ctx, "metrics server", func(ctx context.Context) error {
+		return metricsServer.StartWithGracefulShutdown(ctx, metricsAddr)
+	})
+
+	slog.InfoContext(ctx, "worker started", "queues", projections)
+	select {
+	case <-ctx.Done():
+	case err := <-connectionClosed:
+		// restarts cannot recover a closed connection, exit once in-flight
+		// messages are done so the process is restarted
+		slog.Error("broker connection closed", "error", err)
+		exitCode = 1
+		stop()
+	}
+
+	slog.Info("worker shutting down")
+
+	if err := supervisor.Wait(durationEnv("WORKER_SHUTDOWN_TIMEOUT", defaultShutdownTimeout)); err != nil {
+		slog.Error("worker shutdown failed", "error", err)
+	}
+
+	for _, checkpoint := range checkpoints {
+		checkpoint.Flush(context.Background())
+	}
+}
+
+// queueOptions reads the prefetch, concurrency and ordering of the queue
+// named by env from env+"_PREFETCH", env+"_CONCURRENCY" and env+"_ORDERED",
+// left unset to use the registry defaults.
+func queueOptions(env string) handler.Options {
+	return handler.Options{
+		Prefetch:    intEnv(env + "_PREFETCH"),
+		Concurrency: intEnv(env + "_CONCURRENCY"),
+		Unordered:   os.Getenv(env+"_ORDERED") == "false",
+	}
+}
+
+func intEnv(key string) int {
+	v, _ := strconv.Atoi(os.Getenv(key))
+	return v
+}
+
+// durationEnv parses key as a duration, returning fallback when it is unset
+// and exiting when it is malformed.
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalln(err)
+	}
 
-	done := make(chan struct{})
-	fmt.Println("listening...")
-	<-done
+	return d
 }
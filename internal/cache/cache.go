@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	artistPrefix = "artist:"
+	albumPrefix  = "album:"
+	songPrefix   = "song:"
+)
+
+// leaseTTL bounds how long a miss may take to load before its fill is refused.
+const leaseTTL = 10 * time.Second
+
+type (
+	// Store fills misses under a lease: Reserve hands one out before the
+	// database is read and Fill only stores the value while it is still
+	// held, so a Delete issued in between keeps the stale value out.
+	Store interface {
+		Get(ctx context.Context, key string) ([]byte, bool, error)
+		Reserve(ctx context.Context, key string) (string, error)
+		Fill(ctx context.Context, key, lease string, value []byte) error
+		Delete(ctx context.Context, keys ...string) error
+	}
+)
+
+func ArtistKey(id string) string {
+	return artistPrefix + id
+}
+
+func AlbumKey(id string) string {
+	return albumPrefix + id
+}
+
+func SongKey(id string) string {
+	return songPrefix + id
+}
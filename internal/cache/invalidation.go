@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"context"
+	"cqrs-sample/pkg/event"
+	"cqrs-sample/pkg/message"
+	"cqrs-sample/pkg/song"
+	"log/slog"
+	"time"
+)
+
+type (
+	Deleter interface {
+		Delete(ctx context.Context, keys ...string) error
+	}
+
+	Publisher interface {
+		Publish(ctx context.Context, ev event.Message, key event.Event) error
+	}
+
+	// ProjectionDatabase lists every call the worker projections make, so a
+	// new write has to be added here and decide what it invalidates.
+	ProjectionDatabase interface {
+		CreateArtist(ctx context.Context, artist song.Artist) error
+		MergeArtists(ctx context.Context, merge song.ArtistMerge) error
+		SaveAlbum(ctx context.Context, album song.Album) (bool, error)
+		GetAlbumByID(ctx context.Context, id string) (song.Album, error)
+		GetAlbumsByArtistID(ctx context.Context, artistID string) ([]song.Album, error)
+		GetFollowerIDs(ctx context.Context, artistID string) ([]string, error)
+		AddReleaseToFeeds(ctx context.Context, listenerIDs []string, album song.Album, publishedAt time.Time) error
+		CreateSong(ctx context.Context, s song.Song) error
+		AddSongToAlbum(ctx context.Context, s song.Song) error
+		GetSongByID(ctx context.Context, id string) (song.Song, error)
+		GetSongsByIDs(ctx context.Context, ids []string) ([]song.Song, error)
+		GetSongsFeaturingArtist(ctx context.Context, artistID string) ([]song.Song, error)
+		IncrementSongPlays(ctx context.Context, songID string) error
+		AddSongPlays(ctx context.Context, plays map[string][]string) ([]string, error)
+		CreateListener(ctx context.Context, listener song.Listener) error
+		AddListening(ctx context.Context, listening song.Listening) error
+		CreateFollow(ctx context.Context, follow song.Follow) error
+		AddLike(ctx context.Context, like song.Like) error
+		AddAlbumRating(ctx context.Context, rating song.AlbumRating) error
+		SavePlaylist(ctx context.Context, playlist song.Playlist) error
+	}
+
+	// Invalidating decorates the worker's projection database and drops
+	// the cached entries of every artist, album and song a write touched,
+	// except for plays, which are left to the store TTL.
+	Invalidating struct {
+		db    ProjectionDatabase
+		cache Deleter
+	}
+
+	// Broadcast forwards invalidations to the query services over the
+	// message broker, for stores such as LRU that live in their memory.
+	Broadcast struct {
+		pub Publisher
+	}
+)
+
+func NewInvalidating(db ProjectionDatabase, cache Deleter) *Invalidating {
+	return &Invalidating{
+		db:    db,
+		cache: cache,
+	}
+}
+
+func NewBroadcast(pub Publisher) *Broadcast {
+	return &Broadcast{
+		pub: pub,
+	}
+}
+
+func (b Broadcast) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	msg := event.NewMessage(message.CacheInvalidation{Keys: keys})
+	return b.pub.Publish(ctx, msg, event.CacheInvalidatedEvent)
+}
+
+func (i Invalidating) CreateArtist(ctx context.Context, artist song.Artist) error {
+	if err := i.db.CreateArtist(ctx, artist); err != nil {
+		return err
+	}
+
+	i.invalidate(ctx, ArtistKey(artist.ID))
+	return nil
+}
+
+func (i Invalidating) MergeArtists(ctx context.Context, merge song.ArtistMerge) error {
+	if err := i.db.MergeArtists(ctx, merge); err != nil {
+		return err
+	}
+
+	keys := []string{ArtistKey(merge.SourceID), ArtistKey(merge.Target.ID)}
+	albums, err := i.db.GetAlbumsByArtistID(ctx, merge.Target.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "cache invalidate albums of artist failed", "artist_id", merge.Target.ID, "error", err)
+	}
+
+	for _, album := range albums {
+		keys = append(keys, AlbumKey(album.ID))
+		for _, s := range album.Songs {
+			keys = append(keys, SongKey(s.ID))
+		}
+	}
+
+	featured, err := i.db.GetSongsFeaturingArtist(ctx, merge.Target.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "cache invalidate songs featuring artist failed", "artist_id", merge.Target.ID, "error", err)
+	}
+
+	for _, s := range featured {
+		keys = append(keys, SongKey(s.ID), AlbumKey(s.Album.ID))
+	}
+
+	i.invalidate(ctx, keys...)
+	return nil
+}
+
+func (i Invalidating) SaveAlbum(ctx context.Context, album song.Album) (bool, error) {
+	current, err := i.db.SaveAlbum(ctx, album)
+	if err != nil || !current {
+		return current, err
+	}
+
+	saved, err := i.db.GetAlbumByID(ctx, album.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "cache invalidate songs of album failed", "album_id", album.ID, "error", err)
+	}
+
+	keys := []string{AlbumKey(album.ID)}
+	for _, s := range saved.Songs {
+		keys = append(keys, SongKey(s.ID))
+	}
+
+	i.invalidate(ctx, keys...)
+	return true, nil
+}
+
+func (i Invalidating) CreateSong(ctx context.Context, s song.Song) error {
+	if err := i.db.CreateSong(ctx, s); err != nil {
+		return err
+	}
+
+	i.invalidate(ctx, SongKey(s.ID))
+	return nil
+}
+
+func (i Invalidating) AddSongToAlbum(ctx context.Context, s song.Song) error {
+	if err := i.db.AddSongToAlbum(ctx, s); err != nil {
+		return err
+	}
+
+	i.invalidate(ctx, AlbumKey(s.Album.ID))
+	return nil
+}
+
+// IncrementSongPlays leaves the song cached, see AddSongPlays.
+func (i Invalidating) IncrementSongPlays(ctx context.Context, songID string) error {
+	return i.db.IncrementSongPlays(ctx, songID)
+}
+
+// AddSongPlays leaves the played songs cached: the hottest songs are played
+// on every flush, so invalidating them would keep their entries from ever
+// being served. Their play counts lag by at most the store TTL.
+func (i Invalidating) AddSongPlays(ctx context.Context, plays map[string][]string) ([]string, error) {
+	return i.db.AddSongPlays(ctx, plays)
+}
+
+func (i Invalidating) AddLike(ctx context.Context, like song.Like) error {
+	if err := i.db.AddLike(ctx, like); err != nil {
+		return err
+	}
+
+	i.invalidate(ctx, SongKey(like.Song.ID))
+	return nil
+}
+
+func (i Invalidating) AddAlbumRating(ctx context.Context, rating song.AlbumRating) error {
+	if err := i.db.AddAlbumRating(ctx, rating); err != nil {
+		return err
+	}
+
+	i.invalidate(ctx, AlbumKey(rating.AlbumID))
+	return nil
+}
+
+func (i Invalidating) GetAlbumByID(ctx context.Context, id string) (song.Album, error) {
+	return i.db.GetAlbumByID(ctx, id)
+}
+
+func (i Invalidating) GetAlbumsByArtistID(ctx context.Context, artistID string) ([]song.Album, error) {
+	return i.db.GetAlbumsByArtistID(ctx, artistID)
+}
+
+func (i Invalidating) GetFollowerIDs(ctx context.Context, artistID string) ([]string, error) {
+	return i.db.GetFollowerIDs(ctx, artistID)
+}
+
+func (i Invalidating) AddReleaseToFeeds(ctx context.Context, listenerIDs []string, album song.Album, publishedAt time.Time) error {
+	return i.db.AddReleaseToFeeds(ctx, listenerIDs, album, publishedAt)
+}
+
+func (i Invalidating) GetSongByID(ctx context.Context, id string) (song.Song, error) {
+	return i.db.GetSongByID(ctx, id)
+}
+
+func (i Invalidating) GetSongsByIDs(ctx context.Context, ids []string) ([]song.Song, error) {
+	return i.db.GetSongsByIDs(ctx, ids)
+}
+
+func (i Invalidating) GetSongsFeaturingArtist(ctx context.Context, artistID string) ([]song.Song, error) {
+	return i.db.GetSongsFeaturingArtist(ctx, artistID)
+}
+
+func (i Invalidating) CreateListener(ctx context.Context, listener song.Listener) error {
+	return i.db.CreateListener(ctx, listener)
+}
+
+func (i Invalidating) AddListening(ctx context.Context, listening song.Listening) error {
+	return i.db.AddListening(ctx, listening)
+}
+
+func (i Invalidating) CreateFollow(ctx context.Context, follow song.Follow) error {
+	return i.db.CreateFollow(ctx, follow)
+}
+
+func (i Invalidating) SavePlaylist(ctx context.Context, playlist song.Playlist) error {
+	return i.db.SavePlaylist(ctx, playlist)
+}
+
+// invalidate only logs failures, stale entries expire with the store TTL.
+func (i Invalidating) invalidate(ctx context.Context, keys ...string) {
+	if err := i.cache.Delete(ctx, keys...); err != nil {
+		slog.ErrorContext(ctx, "cache invalidate failed", "keys", keys, "error", err)
+	}
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"cqrs-sample/pkg/song"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type (
+	fakeLibraryDatabase struct {
+		LibraryDatabase
+		songs  map[string]song.Song
+		loaded func()
+	}
+
+	fakeProjectionDatabase struct {
+		ProjectionDatabase
+	}
+)
+
+func (f fakeLibraryDatabase) GetSongByID(_ context.Context, id string) (song.Song, error) {
+	if f.loaded != nil {
+		f.loaded()
+	}
+
+	return f.songs[id], nil
+}
+
+func (f fakeProjectionDatabase) CreateArtist(context.Context, song.Artist) error {
+	return nil
+}
+
+func (f fakeProjectionDatabase) AddLike(context.Context, song.Like) error {
+	return nil
+}
+
+func (f fakeProjectionDatabase) AddSongPlays(_ context.Context, _ map[string][]string) ([]string, error) {
+	return nil, nil
+}
+
+func Test_Library_Caches_Song_On_Miss(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	lru := NewLRU(10, 0)
+	db := fakeLibraryDatabase{songs: map[string]song.Song{"1": {ID: "1", Title: "One"}}}
+	library := NewLibrary(db, lru)
+
+	// Act
+	if _, err := library.GetSongByID(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	if _, ok, _ := lru.Get(ctx, SongKey("1")); !ok {
+		t.Errorf("cached: got = %t, want = true", ok)
+	}
+}
+
+func Test_Library_Does_Not_Cache_Song_Invalidated_While_Loading(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	lru := NewLRU(10, 0)
+	invalidating := NewInvalidating(fakeProjectionDatabase{}, lru)
+	db := fakeLibraryDatabase{songs: map[string]song.Song{"1": {ID: "1", Likes: 1}}}
+	db.loaded = func() {
+		if err := invalidating.AddLike(ctx, song.Like{Song: song.Song{ID: "1"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	library := NewLibrary(db, lru)
+
+	// Act
+	if _, err := library.GetSongByID(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	if _, ok, _ := lru.Get(ctx, SongKey("1")); ok {
+		t.Errorf("cached: got = %t, want = false", ok)
+	}
+}
+
+func Test_Invalidating_Deletes_Written_Keys(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	lru := NewLRU(10, 0)
+	for _, key := range []string{ArtistKey("1"), SongKey("2"), SongKey("3"), SongKey("4")} {
+		reserveAndFill(t, lru, key, "{}")
+	}
+	invalidating := NewInvalidating(fakeProjectionDatabase{}, lru)
+
+	// Act
+	if err := invalidating.CreateArtist(ctx, song.Artist{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"2", "3"} {
+		if err := invalidating.AddLike(ctx, song.Like{Song: song.Song{ID: id}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := invalidating.AddSongPlays(ctx, map[string][]string{"4": {"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	var cached []string
+	for key := range lru.entries {
+		cached = append(cached, key)
+	}
+	sort.Strings(cached)
+
+	if want := []string{SongKey("4")}; !reflect.DeepEqual(cached, want) {
+		t.Errorf("cached: got = %v, want = %v", cached, want)
+	}
+}
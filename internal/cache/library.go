@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"cqrs-sample/pkg/query"
+	"cqrs-sample/pkg/song"
+	"encoding/json"
+	"log/slog"
+)
+
+type (
+	LibraryDatabase interface {
+		GetArtistByID(ctx context.Context, id string) (song.Artist, error)
+		GetAlbumByID(ctx context.Context, id string) (song.Album, error)
+		GetSongByID(ctx context.Context, id string) (song.Song, error)
+		GetAlbumsByArtistID(ctx context.Context, artistID string) ([]song.Album, error)
+		GetSongsFeaturingArtist(ctx context.Context, artistID string) ([]song.Song, error)
+		GetArtistRedirect(ctx context.Context, id string) (string, error)
+	}
+
+	// Library is a read-through decorator for the artist, album and song
+	// lookups of the query service. Lookups by id are served from the store
+	// and filled from the database on a miss; everything else passes through.
+	Library struct {
+		db    LibraryDatabase
+		store Store
+	}
+)
+
+func NewLibrary(db LibraryDatabase, store Store) *Library {
+	return &Library{
+		db:    db,
+		store: store,
+	}
+}
+
+func (l Library) GetArtistByID(ctx context.Context, id string) (song.Artist, error) {
+	return readThrough(ctx, l.store, ArtistKey(id), func() (song.Artist, error) {
+		return l.db.GetArtistByID(ctx, id)
+	})
+}
+
+func (l Library) GetAlbumByID(ctx context.Context, id string) (song.Album, error) {
+	return readThrough(ctx, l.store, AlbumKey(id), func() (song.Album, error) {
+		return l.db.GetAlbumByID(ctx, id)
+	})
+}
+
+func (l Library) GetSongByID(ctx context.Context, id string) (song.Song, error) {
+	return readThrough(ctx, l.store, SongKey(id), func() (song.Song, error) {
+		return l.db.GetSongByID(ctx, id)
+	})
+}
+
+func (l Library) GetAlbumsByArtistID(ctx context.Context, artistID string) ([]song.Album, error) {
+	return l.db.GetAlbumsByArtistID(ctx, artistID)
+}
+
+func (l Library) GetSongsFeaturingArtist(ctx context.Context, artistID string) ([]song.Song, error) {
+	return l.db.GetSongsFeaturingArtist(ctx, artistID)
+}
+
+func (l Library) GetArtistRedirect(ctx context.Context, id string) (string, error) {
+	return l.db.GetArtistRedirect(ctx, id)
+}
+
+// readThrough never fails because of the store: a broken cache degrades to
+// reading the database directly. Fresh reads skip the store and refresh it.
+func readThrough[T any](ctx context.Context, store Store, key string, load func() (T, error)) (T, error) {
+	var value []byte
+	var ok bool
+	var err error
+	if !query.IsFreshRead(ctx) {
+		value, ok, err = store.Get(ctx, key)
+		if err != nil {
+			slog.ErrorContext(ctx, "cache get failed", "key", key, "error", err)
+		}
+	}
+
+	var output T
+	if ok {
+		if err := json.Unmarshal(value, &output); err == nil {
+			return output, nil
+		}
+	}
+
+	lease, leaseErr := store.Reserve(ctx, key)
+	if leaseErr != nil {
+		slog.ErrorContext(ctx, "cache reserve failed", "key", key, "error", leaseErr)
+	}
+
+	output, err = load()
+	if err != nil || leaseErr != nil {
+		return output, err
+	}
+
+	value, err = json.Marshal(output)
+	if err == nil {
+		err = store.Fill(ctx, key, lease, value)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "cache fill failed", "key", key, "error", err)
+	}
+
+	return output, nil
+}
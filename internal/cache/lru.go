@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// LRU is an in-process Store that evicts the least recently used entry
+	// once it holds size entries. Entries older than ttl are treated as
+	// misses; a zero ttl keeps them until evicted or deleted.
+	LRU struct {
+		mu      sync.Mutex
+		size    int
+		ttl     time.Duration
+		order   *list.List
+		entries map[string]*list.Element
+		leases  map[string]lruLease
+		tokens  uint64
+		now     func() time.Time
+	}
+
+	lruEntry struct {
+		key       string
+		value     []byte
+		expiresAt time.Time
+	}
+
+	lruLease struct {
+		token     string
+		expiresAt time.Time
+	}
+)
+
+func NewLRU(size int, ttl time.Duration) *LRU {
+	return &LRU{
+		size:    max(size, 1),
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		leases:  make(map[string]lruLease),
+		now:     time.Now,
+	}
+}
+
+func (c *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && c.now().After(entry.expiresAt) {
+		c.remove(element)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (c *LRU) Reserve(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if len(c.leases) >= c.size {
+		for k, lease := range c.leases {
+			if now.After(lease.expiresAt) {
+				delete(c.leases, k)
+			}
+		}
+	}
+
+	c.tokens++
+	token := strconv.FormatUint(c.tokens, 10)
+	c.leases[key] = lruLease{
+		token:     token,
+		expiresAt: now.Add(leaseTTL),
+	}
+
+	return token, nil
+}
+
+func (c *LRU) Fill(_ context.Context, key, lease string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	held, ok := c.leases[key]
+	if !ok || held.token != lease || now.After(held.expiresAt) {
+		return nil
+	}
+	delete(c.leases, key)
+
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = now.Add(c.ttl)
+	}
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{
+		key:       key,
+		value:     value,
+		expiresAt: expiresAt,
+	})
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+
+	return nil
+}
+
+func (c *LRU) Delete(_ context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.leases, key)
+		if element, ok := c.entries[key]; ok {
+			c.remove(element)
+		}
+	}
+
+	return nil
+}
+
+func (c *LRU) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*lruEntry).key)
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func reserveAndFill(t *testing.T, store Store, key, value string) {
+	t.Helper()
+
+	ctx := context.Background()
+	lease, err := store.Reserve(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Fill(ctx, key, lease, []byte(value)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_LRU_Evicts_Least_Recently_Used(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	lru := NewLRU(2, 0)
+	reserveAndFill(t, lru, "a", "1")
+	reserveAndFill(t, lru, "b", "2")
+	if _, _, err := lru.Get(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	reserveAndFill(t, lru, "c", "3")
+
+	// Assert
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok, _ := lru.Get(ctx, key); ok != want {
+			t.Errorf("%s cached: got = %t, want = %t", key, ok, want)
+		}
+	}
+}
+
+func Test_LRU_Expires_Entries_After_TTL(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	clock := time.Now()
+	lru := NewLRU(2, time.Minute)
+	lru.now = func() time.Time {
+		return clock
+	}
+	reserveAndFill(t, lru, "a", "1")
+
+	// Act
+	_, before, _ := lru.Get(ctx, "a")
+	clock = clock.Add(2 * time.Minute)
+	_, after, _ := lru.Get(ctx, "a")
+
+	// Assert
+	if !before {
+		t.Errorf("cached before ttl: got = %t, want = true", before)
+	}
+
+	if after {
+		t.Errorf("cached after ttl: got = %t, want = false", after)
+	}
+}
+
+func Test_LRU_Refuses_Fill_After_Delete(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	lru := NewLRU(2, 0)
+	lease, err := lru.Reserve(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	if err := lru.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	err = lru.Fill(ctx, "a", lease, []byte("stale"))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("err: got = %v, want = nil", err)
+	}
+
+	if _, ok, _ := lru.Get(ctx, "a"); ok {
+		t.Errorf("cached: got = %t, want = false", ok)
+	}
+}
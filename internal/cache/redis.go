@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+const leasePrefix = "lease:"
+
+// fill stores ARGV[2] under KEYS[1] only while KEYS[2] still holds the lease
+// ARGV[1], so a Delete between Reserve and Fill wins.
+var fill = redis.NewScript(`
+if redis.call("GET", KEYS[2]) ~= ARGV[1] then
+	return 0
+end
+redis.call("DEL", KEYS[2])
+if tonumber(ARGV[3]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+else
+	redis.call("SET", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+type (
+	// Redis is a Store shared by every process pointing at the same server,
+	// so the worker can invalidate entries the query service reads.
+	Redis struct {
+		client redis.UniversalClient
+		prefix string
+		ttl    time.Duration
+	}
+)
+
+func NewRedis(client redis.UniversalClient, prefix string, ttl time.Duration) *Redis {
+	return &Redis{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+func (r Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+func (r Redis) Reserve(ctx context.Context, key string) (string, error) {
+	lease := uuid.NewString()
+	if err := r.client.Set(ctx, r.prefix+leasePrefix+key, lease, leaseTTL).Err(); err != nil {
+		return "", err
+	}
+
+	return lease, nil
+}
+
+func (r Redis) Fill(ctx context.Context, key, lease string, value []byte) error {
+	keys := []string{r.prefix + key, r.prefix + leasePrefix + key}
+	return fill.Run(ctx, r.client, keys, lease, value, r.ttl.Milliseconds()).Err()
+}
+
+func (r Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	prefixed := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, r.prefix+key, r.prefix+leasePrefix+key)
+	}
+
+	return r.client.Del(ctx, prefixed...).Err()
+}
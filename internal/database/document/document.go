@@ -2,42 +2,171 @@ package document
 
 import (
 	"cqrs-sample/pkg/song"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
 )
 
 type (
 	Song struct {
 		ID          string      `bson:"_id"`
 		TrackNumber int         `bson:"track_number"`
+		DiscNumber  int         `bson:"disc_number"`
 		Title       string      `bson:"title"`
+		DurationMs  int64       `bson:"duration_ms"`
+		ISRC        string      `bson:"isrc,omitempty"`
+		Explicit    bool        `bson:"explicit"`
+		Composers   []string    `bson:"composers"`
 		Album       AlbumInSong `bson:"album"`
 		Artist      Artist      `bson:"artist"`
+		Featuring   []Featuring `bson:"featuring"`
 		Plays       int         `bson:"plays"`
+		Likes       int         `bson:"likes"`
+		Version     int         `bson:"version"`
+		UpdatedAt   time.Time   `bson:"updated_at,omitempty"`
+	}
+
+	Featuring struct {
+		Artist Artist `bson:"artist"`
+		Role   string `bson:"role"`
 	}
 
 	SongInAlbum struct {
-		ID          string `bson:"_id"`
-		TrackNumber int    `bson:"track_number"`
-		Title       string `bson:"title"`
+		ID          string      `bson:"_id"`
+		TrackNumber int         `bson:"track_number"`
+		DiscNumber  int         `bson:"disc_number"`
+		Title       string      `bson:"title"`
+		DurationMs  int64       `bson:"duration_ms"`
+		Explicit    bool        `bson:"explicit"`
+		Featuring   []Featuring `bson:"featuring"`
 	}
 
 	Album struct {
-		ID          string        `bson:"_id"`
-		Title       string        `bson:"title"`
-		Artist      Artist        `bson:"artist"`
-		ReleaseYear int           `bson:"release_year"`
-		Songs       []SongInAlbum `bson:"songs"`
+		ID              string        `bson:"_id"`
+		Title           string        `bson:"title"`
+		Artist          Artist        `bson:"artist"`
+		ReleaseYear     int           `bson:"release_year"`
+		Status          string        `bson:"status,omitempty"`
+		ReleaseDate     time.Time     `bson:"release_date,omitempty"`
+		Genres          []string      `bson:"genres"`
+		TotalDurationMs int64         `bson:"total_duration_ms"`
+		RatingAverage   float64       `bson:"rating_average"`
+		RatingCount     int           `bson:"rating_count"`
+		Songs           []SongInAlbum `bson:"songs"`
+		Version         int           `bson:"version"`
+		UpdatedAt       time.Time     `bson:"updated_at,omitempty"`
 	}
 
 	AlbumInSong struct {
-		ID          string `bson:"_id"`
-		Title       string `bson:"title"`
-		ReleaseYear int    `bson:"release_year"`
+		ID          string    `bson:"_id"`
+		Title       string    `bson:"title"`
+		ReleaseYear int       `bson:"release_year"`
+		Status      string    `bson:"status,omitempty"`
+		ReleaseDate time.Time `bson:"release_date,omitempty"`
 	}
 
 	Artist struct {
-		ID     string `bson:"_id"`
-		Name   string `bson:"name"`
-		Gender string `bson:"gender"`
+		ID        string    `bson:"_id"`
+		Name      string    `bson:"name"`
+		Gender    string    `bson:"gender"`
+		Genres    []string  `bson:"genres"`
+		Version   int       `bson:"version,omitempty"`
+		UpdatedAt time.Time `bson:"updated_at,omitempty"`
+	}
+
+	ArtistRedirect struct {
+		ID       string    `bson:"_id"`
+		ArtistID string    `bson:"artist_id"`
+		MergedAt time.Time `bson:"merged_at"`
+	}
+
+	Checkpoint struct {
+		ID              string    `bson:"_id"`
+		LastEventAt     time.Time `bson:"last_event_at"`
+		LastProcessedAt time.Time `bson:"last_processed_at"`
+		Processed       int64     `bson:"processed"`
+		Pending         int       `bson:"pending"`
+		SampledAt       time.Time `bson:"sampled_at"`
+		DrainedAt       time.Time `bson:"drained_at,omitempty"`
+		LastError       string    `bson:"last_error,omitempty"`
+		LastErrorAt     time.Time `bson:"last_error_at,omitempty"`
+	}
+
+	ParkedEvent struct {
+		ID         string                 `bson:"_id"`
+		Handler    string                 `bson:"handler"`
+		Dependency string                 `bson:"dependency"`
+		Body       []byte                 `bson:"body"`
+		Headers    map[string]interface{} `bson:"headers,omitempty"`
+		ParkedAt   time.Time              `bson:"parked_at"`
+	}
+
+	Listener struct {
+		ID    string `bson:"_id"`
+		Name  string `bson:"name"`
+		Email string `bson:"email"`
+	}
+
+	Listening struct {
+		ID         string          `bson:"_id"`
+		ListenerID string          `bson:"listener_id"`
+		Song       SongInListening `bson:"song"`
+		PlayedAt   time.Time       `bson:"played_at"`
+	}
+
+	Follow struct {
+		ID         string    `bson:"_id"`
+		ListenerID string    `bson:"listener_id"`
+		ArtistID   string    `bson:"artist_id"`
+		FollowedAt time.Time `bson:"followed_at"`
+	}
+
+	Like struct {
+		ID         string          `bson:"_id"`
+		ListenerID string          `bson:"listener_id"`
+		Song       SongInListening `bson:"song"`
+		LikedAt    time.Time       `bson:"liked_at"`
+	}
+
+	AlbumRating struct {
+		ID         string    `bson:"_id"`
+		ListenerID string    `bson:"listener_id"`
+		AlbumID    string    `bson:"album_id"`
+		Rating     int       `bson:"rating"`
+		RatedAt    time.Time `bson:"rated_at"`
+	}
+
+	Release struct {
+		ID          string      `bson:"_id"`
+		ListenerID  string      `bson:"listener_id"`
+		Album       AlbumInSong `bson:"album"`
+		Artist      Artist      `bson:"artist"`
+		PublishedAt time.Time   `bson:"published_at"`
+	}
+
+	Playlist struct {
+		ID              string           `bson:"_id"`
+		Name            string           `bson:"name"`
+		ListenerID      string           `bson:"listener_id"`
+		Songs           []SongInPlaylist `bson:"songs"`
+		TotalDurationMs int64            `bson:"total_duration_ms"`
+		Version         int              `bson:"version"`
+	}
+
+	SongInPlaylist struct {
+		Position   int         `bson:"position"`
+		ID         string      `bson:"_id"`
+		Title      string      `bson:"title"`
+		DurationMs int64       `bson:"duration_ms"`
+		Album      AlbumInSong `bson:"album"`
+		Artist     Artist      `bson:"artist"`
+	}
+
+	SongInListening struct {
+		ID          string      `bson:"_id"`
+		TrackNumber int         `bson:"track_number"`
+		Title       string      `bson:"title"`
+		Album       AlbumInSong `bson:"album"`
+		Artist      Artist      `bson:"artist"`
 	}
 )
 
@@ -45,10 +174,19 @@ func (s Song) ToDomain() song.Song {
 	return song.Song{
 		ID:          s.ID,
 		TrackNumber: s.TrackNumber,
+		DiscNumber:  s.DiscNumber,
 		Title:       s.Title,
+		Duration:    time.Duration(s.DurationMs) * time.Millisecond,
+		ISRC:        s.ISRC,
+		Explicit:    s.Explicit,
+		Composers:   s.Composers,
 		Plays:       s.Plays,
+		Likes:       s.Likes,
 		Album:       s.Album.ToDomain(),
 		Artist:      s.Artist.ToDomain(),
+		Featuring:   featuringToDomain(s.Featuring),
+		Version:     s.Version,
+		UpdatedAt:   s.UpdatedAt,
 	}
 }
 
@@ -56,7 +194,18 @@ func (s SongInAlbum) ToDomain() song.Song {
 	return song.Song{
 		ID:          s.ID,
 		TrackNumber: s.TrackNumber,
+		DiscNumber:  s.DiscNumber,
 		Title:       s.Title,
+		Duration:    time.Duration(s.DurationMs) * time.Millisecond,
+		Explicit:    s.Explicit,
+		Featuring:   featuringToDomain(s.Featuring),
+	}
+}
+
+func (f Featuring) ToDomain() song.Featuring {
+	return song.Featuring{
+		Artist: f.Artist.ToDomain(),
+		Role:   song.Role(f.Role),
 	}
 }
 
@@ -71,7 +220,17 @@ func (a Album) ToDomain() song.Album {
 		Title:       a.Title,
 		Artist:      a.Artist.ToDomain(),
 		ReleaseYear: a.ReleaseYear,
-		Songs:       songs,
+		Status:      song.AlbumStatus(a.Status),
+		ReleaseDate: a.ReleaseDate,
+		Genres:      song.NewGenres(a.Genres),
+		Duration:    time.Duration(a.TotalDurationMs) * time.Millisecond,
+		Rating: song.Rating{
+			Average: a.RatingAverage,
+			Count:   a.RatingCount,
+		},
+		Songs:     songs,
+		Version:   a.Version,
+		UpdatedAt: a.UpdatedAt,
 	}
 }
 
@@ -80,22 +239,145 @@ func (a AlbumInSong) ToDomain() song.Album {
 		ID:          a.ID,
 		Title:       a.Title,
 		ReleaseYear: a.ReleaseYear,
+		Status:      song.AlbumStatus(a.Status),
+		ReleaseDate: a.ReleaseDate,
 	}
 }
 
 func (a Artist) ToDomain() song.Artist {
 	return song.Artist{
-		ID:     a.ID,
-		Name:   a.Name,
-		Gender: song.Gender(a.Gender),
+		ID:        a.ID,
+		Name:      a.Name,
+		Gender:    song.Gender(a.Gender),
+		Genres:    song.NewGenres(a.Genres),
+		Version:   a.Version,
+		UpdatedAt: a.UpdatedAt,
+	}
+}
+
+func (l Listener) ToDomain() song.Listener {
+	return song.Listener{
+		ID:    l.ID,
+		Name:  l.Name,
+		Email: l.Email,
+	}
+}
+
+func (l Listening) ToDomain() song.Listening {
+	return song.Listening{
+		ID:         l.ID,
+		ListenerID: l.ListenerID,
+		Song:       l.Song.ToDomain(),
+		PlayedAt:   l.PlayedAt,
+	}
+}
+
+func (s SongInListening) ToDomain() song.Song {
+	return song.Song{
+		ID:          s.ID,
+		TrackNumber: s.TrackNumber,
+		Title:       s.Title,
+		Album:       s.Album.ToDomain(),
+		Artist:      s.Artist.ToDomain(),
+	}
+}
+
+func (p Playlist) ToDomain() song.Playlist {
+	songs := make([]song.Song, len(p.Songs), len(p.Songs))
+	for i, s := range p.Songs {
+		songs[i] = s.ToDomain()
+	}
+
+	return song.Playlist{
+		ID:         p.ID,
+		Name:       p.Name,
+		ListenerID: p.ListenerID,
+		Songs:      songs,
+		Version:    p.Version,
+	}
+}
+
+func (s SongInPlaylist) ToDomain() song.Song {
+	return song.Song{
+		ID:       s.ID,
+		Title:    s.Title,
+		Duration: time.Duration(s.DurationMs) * time.Millisecond,
+		Album:    s.Album.ToDomain(),
+		Artist:   s.Artist.ToDomain(),
+	}
+}
+
+func (l Like) ToDomain() song.Like {
+	return song.Like{
+		ListenerID: l.ListenerID,
+		Song:       l.Song.ToDomain(),
+		LikedAt:    l.LikedAt,
+	}
+}
+
+func (r Release) ToDomain() song.Release {
+	album := r.Album.ToDomain()
+	album.Artist = r.Artist.ToDomain()
+
+	return song.Release{
+		ListenerID:  r.ListenerID,
+		Album:       album,
+		PublishedAt: r.PublishedAt,
+	}
+}
+
+func (c Checkpoint) ToDomain() song.Checkpoint {
+	return song.Checkpoint{
+		Projection:      c.ID,
+		LastEventAt:     c.LastEventAt,
+		LastProcessedAt: c.LastProcessedAt,
+		Processed:       c.Processed,
+		Pending:         c.Pending,
+		SampledAt:       c.SampledAt,
+		DrainedAt:       c.DrainedAt,
+		LastError:       c.LastError,
+		LastErrorAt:     c.LastErrorAt,
+	}
+}
+
+func (p ParkedEvent) ToDomain() song.ParkedEvent {
+	headers := make(map[string]interface{}, len(p.Headers))
+	for k, v := range p.Headers {
+		if t, ok := v.(primitive.DateTime); ok {
+			v = t.Time().UTC()
+		}
+
+		headers[k] = v
+	}
+
+	return song.ParkedEvent{
+		ID:         p.ID,
+		Handler:    p.Handler,
+		Dependency: p.Dependency,
+		Body:       p.Body,
+		Headers:    headers,
+		ParkedAt:   p.ParkedAt,
+	}
+}
+
+func NewParkedEventFromDomain(p song.ParkedEvent) ParkedEvent {
+	return ParkedEvent{
+		ID:         p.ID,
+		Handler:    p.Handler,
+		Dependency: p.Dependency,
+		Body:       p.Body,
+		Headers:    p.Headers,
+		ParkedAt:   p.ParkedAt,
 	}
 }
 
 func NewArtistFromDomain(a song.Artist) Artist {
 	return Artist{
-		ID:     a.ID,
-		Name:   a.Name,
-		Gender: string(a.Gender),
+		ID:      a.ID,
+		Name:    a.Name,
+		Gender:  string(a.Gender),
+		Genres:  song.GenreNames(a.Genres),
+		Version: a.Version,
 	}
 }
 
@@ -106,11 +388,18 @@ func NewAlbumFromDomain(a song.Album) Album {
 	}
 
 	return Album{
-		ID:          a.ID,
-		Title:       a.Title,
-		ReleaseYear: a.ReleaseYear,
-		Artist:      NewArtistFromDomain(a.Artist),
-		Songs:       songs,
+		ID:              a.ID,
+		Title:           a.Title,
+		ReleaseYear:     a.ReleaseYear,
+		Status:          string(a.Status),
+		ReleaseDate:     a.ReleaseDate,
+		Artist:          NewArtistFromDomain(a.Artist),
+		Genres:          song.GenreNames(a.Genres),
+		TotalDurationMs: a.Duration.Milliseconds(),
+		RatingAverage:   a.Rating.Average,
+		RatingCount:     a.Rating.Count,
+		Songs:           songs,
+		Version:         a.Version,
 	}
 }
 
@@ -118,10 +407,18 @@ func NewSongFromDomain(s song.Song) Song {
 	return Song{
 		ID:          s.ID,
 		TrackNumber: s.TrackNumber,
+		DiscNumber:  s.DiscNumber,
 		Title:       s.Title,
+		DurationMs:  s.Duration.Milliseconds(),
+		ISRC:        s.ISRC,
+		Explicit:    s.Explicit,
+		Composers:   s.Composers,
 		Plays:       s.Plays,
+		Likes:       s.Likes,
 		Album:       NewAlbumInSongFromDomain(s.Album),
 		Artist:      NewArtistFromDomain(s.Artist),
+		Featuring:   newFeaturingFromDomain(s.Featuring),
+		Version:     s.Version,
 	}
 }
 
@@ -129,7 +426,18 @@ func NewSongInAlbumFromDomain(s song.Song) SongInAlbum {
 	return SongInAlbum{
 		ID:          s.ID,
 		TrackNumber: s.TrackNumber,
+		DiscNumber:  s.DiscNumber,
 		Title:       s.Title,
+		DurationMs:  s.Duration.Milliseconds(),
+		Explicit:    s.Explicit,
+		Featuring:   newFeaturingFromDomain(s.Featuring),
+	}
+}
+
+func NewFeaturingFromDomain(f song.Featuring) Featuring {
+	return Featuring{
+		Artist: NewArtistFromDomain(f.Artist),
+		Role:   string(f.Role),
 	}
 }
 
@@ -143,5 +451,117 @@ func NewAlbumInSongFromDomain(a song.Album) AlbumInSong {
 		ID:          a.ID,
 		Title:       a.Title,
 		ReleaseYear: a.ReleaseYear,
+		Status:      string(a.Status),
+		ReleaseDate: a.ReleaseDate,
+	}
+}
+
+func NewListenerFromDomain(l song.Listener) Listener {
+	return Listener{
+		ID:    l.ID,
+		Name:  l.Name,
+		Email: l.Email,
+	}
+}
+
+func NewListeningFromDomain(l song.Listening) Listening {
+	return Listening{
+		ID:         l.ID,
+		ListenerID: l.ListenerID,
+		Song:       NewSongInListeningFromDomain(l.Song),
+		PlayedAt:   l.PlayedAt,
+	}
+}
+
+func NewSongInListeningFromDomain(s song.Song) SongInListening {
+	return SongInListening{
+		ID:          s.ID,
+		TrackNumber: s.TrackNumber,
+		Title:       s.Title,
+		Album:       NewAlbumInSongFromDomain(s.Album),
+		Artist:      NewArtistFromDomain(s.Artist),
 	}
 }
+
+func NewPlaylistFromDomain(p song.Playlist) Playlist {
+	songs := make([]SongInPlaylist, len(p.Songs), len(p.Songs))
+	for i, s := range p.Songs {
+		songs[i] = NewSongInPlaylistFromDomain(s, i+1)
+	}
+
+	return Playlist{
+		ID:              p.ID,
+		Name:            p.Name,
+		ListenerID:      p.ListenerID,
+		Songs:           songs,
+		TotalDurationMs: p.Duration().Milliseconds(),
+		Version:         p.Version,
+	}
+}
+
+func NewSongInPlaylistFromDomain(s song.Song, position int) SongInPlaylist {
+	return SongInPlaylist{
+		Position:   position,
+		ID:         s.ID,
+		Title:      s.Title,
+		DurationMs: s.Duration.Milliseconds(),
+		Album:      NewAlbumInSongFromDomain(s.Album),
+		Artist:     NewArtistFromDomain(s.Artist),
+	}
+}
+
+func NewFollowFromDomain(f song.Follow) Follow {
+	return Follow{
+		ID:         f.ListenerID + ":" + f.ArtistID,
+		ListenerID: f.ListenerID,
+		ArtistID:   f.ArtistID,
+		FollowedAt: f.FollowedAt,
+	}
+}
+
+func NewReleaseFromDomain(r song.Release) Release {
+	return Release{
+		ID:          r.ListenerID + ":" + r.Album.ID,
+		ListenerID:  r.ListenerID,
+		Album:       NewAlbumInSongFromDomain(r.Album),
+		Artist:      NewArtistFromDomain(r.Album.Artist),
+		PublishedAt: r.PublishedAt,
+	}
+}
+
+func NewLikeFromDomain(l song.Like) Like {
+	return Like{
+		ID:         l.ListenerID + ":" + l.Song.ID,
+		ListenerID: l.ListenerID,
+		Song:       NewSongInListeningFromDomain(l.Song),
+		LikedAt:    l.LikedAt,
+	}
+}
+
+func NewAlbumRatingFromDomain(r song.AlbumRating) AlbumRating {
+	return AlbumRating{
+		ID:         r.ListenerID + ":" + r.AlbumID,
+		ListenerID: r.ListenerID,
+		AlbumID:    r.AlbumID,
+		Rating:     r.Rating,
+		RatedAt:    r.RatedAt,
+	}
+}
+
+func newFeaturingFromDomain(featuring []song.Featuring) []Featuring {
+	output := make([]Featuring, len(featuring), len(featuring))
+	for i, f := range featuring {
+		output[i] = NewFeaturingFromDomain(f)
+	}
+
+	return output
+}
+
+func featuringToDomain(featuring []Featuring) []song.Featuring {
+	var output []song.Featuring
+	for _, f := range featuring {
+		output = append(output, f.ToDomain())
+	}
+
+	return output
+}
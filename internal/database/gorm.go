@@ -4,7 +4,11 @@ import (
 	"context"
 	"cqrs-sample/internal/database/model"
 	"cqrs-sample/pkg/song"
+	"errors"
+	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type (
@@ -15,9 +19,20 @@ type (
 
 func NewGorm(db *gorm.DB) *Gorm {
 	_ = db.AutoMigrate(
+		&model.Genre{},
 		&model.Artist{},
 		&model.Album{},
 		&model.Song{},
+		&model.SongFeaturing{},
+		&model.Listener{},
+		&model.Follow{},
+		&model.Like{},
+		&model.AlbumRating{},
+		&model.Playlist{},
+		&model.PlaylistSong{},
+		&model.ArtistRedirect{},
+		&model.ImportJob{},
+		&model.ImportRow{},
 	)
 
 	return &Gorm{
@@ -37,13 +52,86 @@ func (g Gorm) CreateArtist(ctx context.Context, artist *song.Artist) error {
 
 func (g Gorm) GetArtistByID(ctx context.Context, id string) (song.Artist, error) {
 	m := model.Artist{ID: id}
-	if err := g.db.WithContext(ctx).First(&m).Error; err != nil {
-		return song.Artist{}, err
+	if err := g.db.WithContext(ctx).Preload("Genres").First(&m).Error; err != nil {
+		return song.Artist{}, gormErr(err)
+	}
+
+	return m.ToDomain(), nil
+}
+
+func (g Gorm) GetArtistByName(ctx context.Context, name string) (song.Artist, error) {
+	var m model.Artist
+	err := g.db.WithContext(ctx).
+		Preload("Genres").
+		Where("name = ?", name).
+		Order("id").
+		First(&m).Error
+	if err != nil {
+		return song.Artist{}, gormErr(err)
 	}
 
 	return m.ToDomain(), nil
 }
 
+// MergeArtists moves everything credited to the source artist over to the
+// target and removes the source, leaving a redirect behind. Credits the
+// target already holds are kept once, and featurings that would credit an
+// artist on its own song are dropped. The merge only applies while the
+// target is still at the version it was read at.
+func (g Gorm) MergeArtists(ctx context.Context, sourceID string, target song.Artist, mergedAt time.Time) error {
+	targetID := target.ID
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec("UPDATE artists SET version = version + 1 WHERE id = ? AND version = ?", targetID, target.Version)
+		if err := result.Error; err != nil {
+			return err
+		}
+
+		if result.RowsAffected == 0 {
+			return song.VersionConflictErr
+		}
+
+		statements := []struct {
+			sql  string
+			args []interface{}
+		}{
+			{"UPDATE albums SET artist_id = ?, version = version + 1 WHERE artist_id = ?", []interface{}{targetID, sourceID}},
+			{"UPDATE songs SET artist_id = ?, version = version + 1 WHERE artist_id = ?", []interface{}{targetID, sourceID}},
+			{
+				"DELETE FROM song_featurings WHERE artist_id = ? AND song_id IN (SELECT song_id FROM song_featurings WHERE artist_id = ?)",
+				[]interface{}{sourceID, targetID},
+			},
+			{"UPDATE song_featurings SET artist_id = ? WHERE artist_id = ?", []interface{}{targetID, sourceID}},
+			{
+				"DELETE FROM song_featurings WHERE artist_id = ? AND song_id IN (SELECT id FROM songs WHERE artist_id = ?)",
+				[]interface{}{targetID, targetID},
+			},
+			{
+				"DELETE FROM follows WHERE artist_id = ? AND listener_id IN (SELECT listener_id FROM follows WHERE artist_id = ?)",
+				[]interface{}{sourceID, targetID},
+			},
+			{"UPDATE follows SET artist_id = ? WHERE artist_id = ?", []interface{}{targetID, sourceID}},
+			{
+				"INSERT INTO artist_genres (artist_id, genre_name) SELECT ?, genre_name FROM artist_genres WHERE artist_id = ? ON CONFLICT DO NOTHING",
+				[]interface{}{targetID, sourceID},
+			},
+			{"DELETE FROM artist_genres WHERE artist_id = ?", []interface{}{sourceID}},
+			{"DELETE FROM artists WHERE id = ?", []interface{}{sourceID}},
+			{"UPDATE artist_redirects SET target_id = ? WHERE target_id = ?", []interface{}{targetID, sourceID}},
+		}
+		for _, st := range statements {
+			if err := tx.Exec(st.sql, st.args...).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&model.ArtistRedirect{
+			ArtistID: sourceID,
+			TargetID: targetID,
+			MergedAt: mergedAt,
+		}).Error
+	})
+}
+
 func (g Gorm) CreateAlbum(ctx context.Context, album *song.Album) error {
 	m := model.NewAlbumFromDomain(*album)
 	if err := g.db.WithContext(ctx).Create(&m).Error; err != nil {
@@ -56,13 +144,102 @@ func (g Gorm) CreateAlbum(ctx context.Context, album *song.Album) error {
 
 func (g Gorm) GetAlbumByID(ctx context.Context, id string) (song.Album, error) {
 	m := model.Album{ID: id}
-	if err := g.db.WithContext(ctx).First(&m).Error; err != nil {
-		return song.Album{}, err
+	if err := g.db.WithContext(ctx).Preload("Genres").First(&m).Error; err != nil {
+		return song.Album{}, gormErr(err)
 	}
 
 	return m.ToDomain(), nil
 }
 
+func (g Gorm) GetAlbumByArtistAndTitle(ctx context.Context, artistID, title string) (song.Album, error) {
+	var m model.Album
+	err := g.db.WithContext(ctx).
+		Preload("Genres").
+		Where("artist_id = ? AND title = ?", artistID, title).
+		Order("id").
+		First(&m).Error
+	if err != nil {
+		return song.Album{}, gormErr(err)
+	}
+
+	return m.ToDomain(), nil
+}
+
+// ScheduleAlbum saves the album only if it is still at the version it was
+// read at, moving it to the next one.
+func (g Gorm) ScheduleAlbum(ctx context.Context, album *song.Album) error {
+	m := model.NewAlbumFromDomain(*album)
+	result := g.db.WithContext(ctx).
+		Model(&model.Album{}).
+		Where("id = ? AND version = ?", m.ID, m.Version).
+		Updates(map[string]interface{}{
+			"status":       m.Status,
+			"release_date": m.ReleaseDate,
+			"version":      m.Version + 1,
+		})
+	if err := result.Error; err != nil {
+		return err
+	}
+
+	if result.RowsAffected == 0 {
+		return song.VersionConflictErr
+	}
+
+	album.Version = m.Version + 1
+	return nil
+}
+
+// GetAlbumsDueForRelease returns the scheduled albums whose release date has
+// passed and the published ones whose release was not announced yet.
+func (g Gorm) GetAlbumsDueForRelease(ctx context.Context, now time.Time) ([]song.Album, error) {
+	var models []model.Album
+	err := g.db.WithContext(ctx).
+		Preload("Genres").
+		Preload("Artist.Genres").
+		Where("(status = ? AND release_date <= ?) OR release_pending", string(song.ScheduledStatus), now).
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]song.Album, len(models), len(models))
+	for i, m := range models {
+		output[i] = m.ToDomain()
+	}
+	return output, nil
+}
+
+// MarkAlbumPublished publishes the album only if it is still scheduled at
+// the version it was read at, leaving its release pending until announced.
+func (g Gorm) MarkAlbumPublished(ctx context.Context, album *song.Album) (bool, error) {
+	result := g.db.WithContext(ctx).
+		Model(&model.Album{}).
+		Where("id = ? AND status = ? AND version = ?", album.ID, string(song.ScheduledStatus), album.Version).
+		Updates(map[string]interface{}{
+			"status":          string(song.PublishedStatus),
+			"version":         album.Version + 1,
+			"release_pending": true,
+		})
+	if err := result.Error; err != nil {
+		return false, err
+	}
+
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	album.Status = song.PublishedStatus
+	album.Version++
+	return true, nil
+}
+
+func (g Gorm) MarkAlbumReleaseAnnounced(ctx context.Context, album song.Album) error {
+	return g.db.WithContext(ctx).
+		Model(&model.Album{}).
+		Where("id = ? AND version = ?", album.ID, album.Version).
+		Update("release_pending", false).Error
+}
+
 func (g Gorm) CreateSong(ctx context.Context, s *song.Song) error {
 	m := model.NewSongFromDomain(*s)
 	if err := g.db.WithContext(ctx).Create(&m).Error; err != nil {
@@ -72,3 +249,224 @@ func (g Gorm) CreateSong(ctx context.Context, s *song.Song) error {
 	s.ID = m.ID
 	return nil
 }
+
+func (g Gorm) GetSongByID(ctx context.Context, id string) (song.Song, error) {
+	m := model.Song{ID: id}
+	if err := g.db.WithContext(ctx).Preload("Featuring").First(&m).Error; err != nil {
+		return song.Song{}, gormErr(err)
+	}
+
+	return m.ToDomain(), nil
+}
+
+func (g Gorm) GetSongByPosition(ctx context.Context, albumID string, discNumber, trackNumber int) (song.Song, error) {
+	var m model.Song
+	err := g.db.WithContext(ctx).
+		Preload("Featuring").
+		Where("album_id = ? AND disc_number = ? AND track_number = ?", albumID, discNumber, trackNumber).
+		First(&m).Error
+	if err != nil {
+		return song.Song{}, gormErr(err)
+	}
+
+	return m.ToDomain(), nil
+}
+
+func (g Gorm) ExistsSongWithISRC(ctx context.Context, isrc string) (bool, error) {
+	var count int64
+	err := g.db.WithContext(ctx).
+		Model(&model.Song{}).
+		Where("isrc = ?", isrc).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (g Gorm) CreateListener(ctx context.Context, listener *song.Listener) error {
+	m := model.NewListenerFromDomain(*listener)
+	if err := g.db.WithContext(ctx).Create(&m).Error; err != nil {
+		return gormErr(err)
+	}
+
+	listener.ID = m.ID
+	return nil
+}
+
+func (g Gorm) GetListenerByID(ctx context.Context, id string) (song.Listener, error) {
+	m := model.Listener{ID: id}
+	if err := g.db.WithContext(ctx).First(&m).Error; err != nil {
+		return song.Listener{}, gormErr(err)
+	}
+
+	return m.ToDomain(), nil
+}
+
+func (g Gorm) CreateFollow(ctx context.Context, follow song.Follow) error {
+	m := model.NewFollowFromDomain(follow)
+	return g.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&m).Error
+}
+
+func (g Gorm) CreateLike(ctx context.Context, like song.Like) (bool, error) {
+	m := model.NewLikeFromDomain(like)
+	result := g.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&m)
+	if err := result.Error; err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (g Gorm) SaveAlbumRating(ctx context.Context, rating song.AlbumRating) error {
+	m := model.NewAlbumRatingFromDomain(rating)
+	return g.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&m).Error
+}
+
+func (g Gorm) CreatePlaylist(ctx context.Context, playlist *song.Playlist) error {
+	m := model.NewPlaylistFromDomain(*playlist)
+	if err := g.db.WithContext(ctx).Create(&m).Error; err != nil {
+		return err
+	}
+
+	playlist.ID = m.ID
+	playlist.Version = m.Version
+	return nil
+}
+
+func (g Gorm) GetPlaylistByID(ctx context.Context, id string) (song.Playlist, error) {
+	m := model.Playlist{ID: id}
+	err := g.db.WithContext(ctx).
+		Preload("Songs", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position")
+		}).
+		First(&m).Error
+	if err != nil {
+		return song.Playlist{}, gormErr(err)
+	}
+
+	return m.ToDomain(), nil
+}
+
+// SavePlaylist saves the playlist only if it is still at the version it was
+// read at, moving it to the next one.
+func (g Gorm) SavePlaylist(ctx context.Context, playlist *song.Playlist) error {
+	m := model.NewPlaylistFromDomain(*playlist)
+
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Playlist{}).
+			Where("id = ? AND version = ?", m.ID, m.Version).
+			Updates(map[string]interface{}{"name": m.Name, "version": m.Version + 1})
+		if err := result.Error; err != nil {
+			return err
+		}
+
+		if result.RowsAffected == 0 {
+			return song.VersionConflictErr
+		}
+
+		if err := tx.Where("playlist_id = ?", m.ID).Delete(&model.PlaylistSong{}).Error; err != nil {
+			return err
+		}
+
+		if len(m.Songs) == 0 {
+			return nil
+		}
+
+		return tx.Create(&m.Songs).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	playlist.Version = m.Version + 1
+	return nil
+}
+
+func (g Gorm) CreateImportJob(ctx context.Context, job *song.ImportJob) error {
+	m := model.NewImportJobFromDomain(*job)
+	if err := g.db.WithContext(ctx).Create(&m).Error; err != nil {
+		return err
+	}
+
+	job.CreatedAt = m.CreatedAt
+	job.UpdatedAt = m.UpdatedAt
+	return nil
+}
+
+func (g Gorm) GetImportJobByID(ctx context.Context, id string) (song.ImportJob, error) {
+	m := model.ImportJob{ID: id}
+	err := g.db.WithContext(ctx).
+		Preload("Rows", func(db *gorm.DB) *gorm.DB {
+			return db.Order("line")
+		}).
+		First(&m).Error
+	if err != nil {
+		return song.ImportJob{}, gormErr(err)
+	}
+
+	return m.ToDomain(), nil
+}
+
+func (g Gorm) SaveImportRow(ctx context.Context, jobID string, row song.ImportRow) error {
+	m := model.NewImportRowFromDomain(jobID, row)
+	return g.db.WithContext(ctx).
+		Model(&model.ImportRow{JobID: m.JobID, Line: m.Line}).
+		Updates(map[string]interface{}{
+			"status":      m.Status,
+			"resource_id": m.ResourceID,
+			"error":       m.Error,
+		}).Error
+}
+
+func (g Gorm) UpdateImportJobStatus(ctx context.Context, id string, status song.ImportStatus) error {
+	return g.db.WithContext(ctx).
+		Model(&model.ImportJob{ID: id}).
+		Update("status", string(status)).Error
+}
+
+// ClaimImportJob moves the job to running with a lease until now plus lease,
+// unless a run holds an unexpired lease on it or it has completed, reporting
+// whether this call claimed it.
+func (g Gorm) ClaimImportJob(ctx context.Context, id string, now time.Time, lease time.Duration) (bool, error) {
+	result := g.db.WithContext(ctx).
+		Model(&model.ImportJob{}).
+		Where("id = ?", id).
+		Where(
+			g.db.Where("status NOT IN ?", []string{string(song.ImportRunning), string(song.ImportCompleted)}).
+				Or("status = ? AND lease_expires_at < ?", string(song.ImportRunning), now),
+		).
+		Updates(map[string]interface{}{
+			"status":           string(song.ImportRunning),
+			"lease_expires_at": now.Add(lease),
+		})
+	if err := result.Error; err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// RenewImportLease extends the lease of a running job until the given time.
+func (g Gorm) RenewImportLease(ctx context.Context, id string, until time.Time) error {
+	return g.db.WithContext(ctx).
+		Model(&model.ImportJob{}).
+		Where("id = ? AND status = ?", id, string(song.ImportRunning)).
+		Update("lease_expires_at", until).Error
+}
+
+func gormErr(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %w", song.NotFoundErr, err)
+	}
+
+	// only reported when the connection is opened with TranslateError
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return fmt.Errorf("%w: %w", song.AlreadyExistsErr, err)
+	}
+
+	return err
+}
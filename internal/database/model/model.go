@@ -1,32 +1,123 @@
 package model
 
-import "cqrs-sample/pkg/song"
+import (
+	"cqrs-sample/pkg/song"
+	"time"
+)
 
 type (
 	Song struct {
 		ID          string `gorm:"primarykey"`
 		TrackNumber int
+		DiscNumber  int
 		Title       string
+		DurationMs  int64
+		ISRC        *string `gorm:"uniqueIndex"`
+		Explicit    bool
+		Composers   []string `gorm:"serializer:json"`
 		Album       Album
 		AlbumID     string
 		Artist      Artist
 		ArtistID    string
+		Featuring   []SongFeaturing
+		Version     int `gorm:"not null;default:1"`
+	}
+
+	SongFeaturing struct {
+		SongID   string `gorm:"primarykey"`
+		ArtistID string `gorm:"primarykey;index"`
+		Role     string
 	}
 
 	Album struct {
-		ID          string `gorm:"primarykey"`
-		Title       string
-		Artist      Artist
-		ArtistID    string
-		ReleaseYear int
-		Songs       []Song
+		ID             string `gorm:"primarykey"`
+		Title          string
+		Artist         Artist
+		ArtistID       string
+		ReleaseYear    int
+		Status         string `gorm:"index"`
+		ReleaseDate    *time.Time
+		Genres         []Genre `gorm:"many2many:album_genres"`
+		Songs          []Song
+		Version        int  `gorm:"not null;default:1"`
+		ReleasePending bool `gorm:"not null;default:false;index"`
 	}
 
 	Artist struct {
-		ID     string `gorm:"primarykey"`
-		Name   string
-		Gender string
-		Albums []Album
+		ID      string `gorm:"primarykey"`
+		Name    string
+		Gender  string
+		Genres  []Genre `gorm:"many2many:artist_genres"`
+		Albums  []Album
+		Version int `gorm:"not null;default:1"`
+	}
+
+	Genre struct {
+		Name string `gorm:"primarykey"`
+	}
+
+	ArtistRedirect struct {
+		ArtistID string `gorm:"primarykey"`
+		TargetID string `gorm:"index"`
+		MergedAt time.Time
+	}
+
+	Listener struct {
+		ID    string `gorm:"primarykey"`
+		Name  string
+		Email string `gorm:"uniqueIndex"`
+	}
+
+	Follow struct {
+		ListenerID string `gorm:"primarykey"`
+		ArtistID   string `gorm:"primarykey;index"`
+		FollowedAt time.Time
+	}
+
+	Like struct {
+		ListenerID string `gorm:"primarykey"`
+		SongID     string `gorm:"primarykey"`
+		LikedAt    time.Time
+	}
+
+	AlbumRating struct {
+		ListenerID string `gorm:"primarykey"`
+		AlbumID    string `gorm:"primarykey"`
+		Rating     int
+		RatedAt    time.Time
+	}
+
+	Playlist struct {
+		ID         string `gorm:"primarykey"`
+		Name       string
+		ListenerID string
+		Version    int
+		Songs      []PlaylistSong
+	}
+
+	PlaylistSong struct {
+		PlaylistID string `gorm:"primarykey"`
+		SongID     string `gorm:"primarykey"`
+		Position   int
+	}
+
+	ImportJob struct {
+		ID             string `gorm:"primarykey"`
+		Format         string
+		Status         string
+		LeaseExpiresAt time.Time
+		CreatedAt      time.Time
+		UpdatedAt      time.Time
+		Rows           []ImportRow `gorm:"foreignKey:JobID"`
+	}
+
+	ImportRow struct {
+		JobID      string            `gorm:"primarykey"`
+		Line       int               `gorm:"primarykey"`
+		Record     song.ImportRecord `gorm:"serializer:json"`
+		Status     string
+		ResourceID string
+		Error      string
 	}
 )
 
@@ -37,37 +128,127 @@ func (a Artist) ToDomain() song.Artist {
 	}
 
 	return song.Artist{
-		ID:     a.ID,
-		Name:   a.Name,
-		Gender: song.Gender(a.Gender),
-		Albums: albums,
+		ID:      a.ID,
+		Name:    a.Name,
+		Gender:  song.Gender(a.Gender),
+		Genres:  genresToDomain(a.Genres),
+		Albums:  albums,
+		Version: a.Version,
 	}
 }
 
 func (a Album) ToDomain() song.Album {
+	artist := a.Artist.ToDomain()
+	artist.ID = a.ArtistID
+
+	var releaseDate time.Time
+	if a.ReleaseDate != nil {
+		releaseDate = *a.ReleaseDate
+	}
+
 	return song.Album{
 		ID:          a.ID,
 		Title:       a.Title,
-		Artist:      a.Artist.ToDomain(),
+		Artist:      artist,
 		ReleaseYear: a.ReleaseYear,
+		Status:      song.AlbumStatus(a.Status),
+		ReleaseDate: releaseDate,
+		Genres:      genresToDomain(a.Genres),
+		Version:     a.Version,
 	}
 }
 
 func (s Song) ToDomain() song.Song {
+	var featuring []song.Featuring
+	for _, f := range s.Featuring {
+		featuring = append(featuring, song.Featuring{
+			Artist: song.Artist{ID: f.ArtistID},
+			Role:   song.Role(f.Role),
+		})
+	}
+
+	album := s.Album.ToDomain()
+	album.ID = s.AlbumID
+	artist := s.Artist.ToDomain()
+	artist.ID = s.ArtistID
+
+	var isrc string
+	if s.ISRC != nil {
+		isrc = *s.ISRC
+	}
+
 	return song.Song{
 		ID:          s.ID,
 		TrackNumber: s.TrackNumber,
+		DiscNumber:  s.DiscNumber,
 		Title:       s.Title,
-		Album:       s.Album.ToDomain(),
-		Artist:      s.Artist.ToDomain(),
+		Duration:    time.Duration(s.DurationMs) * time.Millisecond,
+		ISRC:        isrc,
+		Explicit:    s.Explicit,
+		Composers:   s.Composers,
+		Album:       album,
+		Artist:      artist,
+		Featuring:   featuring,
+		Version:     s.Version,
+	}
+}
+
+func (l Listener) ToDomain() song.Listener {
+	return song.Listener{
+		ID:    l.ID,
+		Name:  l.Name,
+		Email: l.Email,
+	}
+}
+
+func (p Playlist) ToDomain() song.Playlist {
+	songs := make([]song.Song, len(p.Songs), len(p.Songs))
+	for i, s := range p.Songs {
+		songs[i] = song.Song{ID: s.SongID}
+	}
+
+	return song.Playlist{
+		ID:         p.ID,
+		Name:       p.Name,
+		ListenerID: p.ListenerID,
+		Songs:      songs,
+		Version:    p.Version,
+	}
+}
+
+func (j ImportJob) ToDomain() song.ImportJob {
+	rows := make([]song.ImportRow, len(j.Rows), len(j.Rows))
+	for i, row := range j.Rows {
+		rows[i] = row.ToDomain()
+	}
+
+	return song.ImportJob{
+		ID:        j.ID,
+		Format:    j.Format,
+		Status:    song.ImportStatus(j.Status),
+		Rows:      rows,
+		CreatedAt: j.CreatedAt,
+		UpdatedAt: j.UpdatedAt,
+	}
+}
+
+func (r ImportRow) ToDomain() song.ImportRow {
+	return song.ImportRow{
+		Line:       r.Line,
+		Record:     r.Record,
+		Status:     song.RowStatus(r.Status),
+		ResourceID: r.ResourceID,
+		Error:      r.Error,
 	}
 }
 
 func NewArtistFromDomain(a song.Artist) Artist {
 	return Artist{
-		ID:     a.ID,
-		Name:   a.Name,
-		Gender: string(a.Gender),
+		ID:      a.ID,
+		Name:    a.Name,
+		Gender:  string(a.Gender),
+		Genres:  newGenresFromDomain(a.Genres),
+		Version: a.Version,
 	}
 }
 
@@ -77,24 +258,151 @@ func NewAlbumFromDomain(a song.Album) Album {
 		songs[i] = NewSongFromDomain(s)
 	}
 
+	var releaseDate *time.Time
+	if !a.ReleaseDate.IsZero() {
+		releaseDate = &a.ReleaseDate
+	}
+
 	return Album{
 		ID:          a.ID,
 		Title:       a.Title,
 		Artist:      NewArtistFromDomain(a.Artist),
 		ArtistID:    a.Artist.ID,
 		ReleaseYear: a.ReleaseYear,
+		Status:      string(a.Status),
+		ReleaseDate: releaseDate,
+		Genres:      newGenresFromDomain(a.Genres),
 		Songs:       songs,
+		Version:     a.Version,
 	}
 }
 
 func NewSongFromDomain(s song.Song) Song {
+	var featuring []SongFeaturing
+	for _, f := range s.Featuring {
+		featuring = append(featuring, SongFeaturing{
+			SongID:   s.ID,
+			ArtistID: f.Artist.ID,
+			Role:     string(f.Role),
+		})
+	}
+
+	var isrc *string
+	if s.ISRC != "" {
+		isrc = &s.ISRC
+	}
+
 	return Song{
 		ID:          s.ID,
 		TrackNumber: s.TrackNumber,
+		DiscNumber:  s.DiscNumber,
 		Title:       s.Title,
+		DurationMs:  s.Duration.Milliseconds(),
+		ISRC:        isrc,
+		Explicit:    s.Explicit,
+		Composers:   s.Composers,
 		Album:       NewAlbumFromDomain(s.Album),
 		AlbumID:     s.Album.ID,
 		Artist:      NewArtistFromDomain(s.Artist),
 		ArtistID:    s.Artist.ID,
+		Featuring:   featuring,
+		Version:     s.Version,
+	}
+}
+
+func NewListenerFromDomain(l song.Listener) Listener {
+	return Listener{
+		ID:    l.ID,
+		Name:  l.Name,
+		Email: l.Email,
+	}
+}
+
+func NewPlaylistFromDomain(p song.Playlist) Playlist {
+	songs := make([]PlaylistSong, len(p.Songs), len(p.Songs))
+	for i, s := range p.Songs {
+		songs[i] = PlaylistSong{
+			PlaylistID: p.ID,
+			SongID:     s.ID,
+			Position:   i + 1,
+		}
+	}
+
+	return Playlist{
+		ID:         p.ID,
+		Name:       p.Name,
+		ListenerID: p.ListenerID,
+		Version:    p.Version,
+		Songs:      songs,
+	}
+}
+
+func NewImportJobFromDomain(j song.ImportJob) ImportJob {
+	rows := make([]ImportRow, len(j.Rows), len(j.Rows))
+	for i, row := range j.Rows {
+		rows[i] = NewImportRowFromDomain(j.ID, row)
 	}
+
+	return ImportJob{
+		ID:        j.ID,
+		Format:    j.Format,
+		Status:    string(j.Status),
+		CreatedAt: j.CreatedAt,
+		UpdatedAt: j.UpdatedAt,
+		Rows:      rows,
+	}
+}
+
+func NewImportRowFromDomain(jobID string, r song.ImportRow) ImportRow {
+	return ImportRow{
+		JobID:      jobID,
+		Line:       r.Line,
+		Record:     r.Record,
+		Status:     string(r.Status),
+		ResourceID: r.ResourceID,
+		Error:      r.Error,
+	}
+}
+
+func NewFollowFromDomain(f song.Follow) Follow {
+	return Follow{
+		ListenerID: f.ListenerID,
+		ArtistID:   f.ArtistID,
+		FollowedAt: f.FollowedAt,
+	}
+}
+
+func NewLikeFromDomain(l song.Like) Like {
+	return Like{
+		ListenerID: l.ListenerID,
+		SongID:     l.Song.ID,
+		LikedAt:    l.LikedAt,
+	}
+}
+
+func NewAlbumRatingFromDomain(r song.AlbumRating) AlbumRating {
+	return AlbumRating{
+		ListenerID: r.ListenerID,
+		AlbumID:    r.AlbumID,
+		Rating:     r.Rating,
+		RatedAt:    r.RatedAt,
+	}
+}
+
+func newGenresFromDomain(genres []song.Genre) []Genre {
+	var output []Genre
+	for _, genre := range genres {
+		output = append(output, Genre{Name: string(genre)})
+	}
+
+	return output
+}
+
+func genresToDomain(genres []Genre) []song.Genre {
+	var output []song.Genre
+	for _, genre := range genres {
+		output = append(output, song.Genre(genre.Name))
+	}
+
+	return output
 }
@@ -4,16 +4,38 @@ import (
 	"context"
 	"cqrs-sample/internal/database/document"
 	"cqrs-sample/pkg/song"
+	"errors"
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
 )
 
 const (
-	artistCollectionName = "artists"
-	albumsCollectionName = "albums"
-	songCollectionName   = "songs"
+	artistCollectionName           = "artists"
+	albumsCollectionName           = "albums"
+	songCollectionName             = "songs"
+	listenersCollectionName        = "listeners"
+	listeningHistoryCollectionName = "listening_history"
+	playlistsCollectionName        = "playlists"
+	followsCollectionName          = "follows"
+	feedCollectionName             = "feed"
+	likesCollectionName            = "likes"
+	ratingsCollectionName          = "ratings"
+	redirectsCollectionName        = "artist_redirects"
+	checkpointsCollectionName      = "projection_checkpoints"
+	parkedEventsCollectionName     = "parked_events"
+
+	recentPlaysLimit = 1000
 )
 
+var unreleasedStatuses = []string{string(song.DraftStatus), string(song.ScheduledStatus)}
+
+// songProjection leaves out the play ids kept on songs to dedupe plays,
+// which grow up to recentPlaysLimit and are never part of a read.
+var songProjection = bson.M{"recent_plays": 0}
+
 type (
 	Mongo struct {
 		db *mongo.Database
@@ -28,38 +50,116 @@ func NewMongo(db *mongo.Database) (*Mongo, error) {
 
 func (m Mongo) CreateArtist(ctx context.Context, artist song.Artist) error {
 	doc := document.NewArtistFromDomain(artist)
-	_, err := m.db.Collection(artistCollectionName).InsertOne(ctx, doc)
+	doc.UpdatedAt = time.Now()
+	_, err := m.db.Collection(artistCollectionName).
+		ReplaceOne(ctx, newerVersion(doc.ID, doc.Version), doc, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
 	return err
 }
 
-func (m Mongo) CreateAlbum(ctx context.Context, album song.Album) error {
+// SaveAlbum projects the album unless a newer version is stored, reporting
+// whether the album is now at this version. A redelivered event finds its
+// own version and reports true, so work that follows the save is retried.
+func (m Mongo) SaveAlbum(ctx context.Context, album song.Album) (bool, error) {
 	doc := document.NewAlbumFromDomain(album)
-	_, err := m.db.Collection(albumsCollectionName).InsertOne(ctx, doc)
-	return err
+	update := bson.M{
+		"$set": bson.M{
+			"title":        doc.Title,
+			"artist":       doc.Artist,
+			"release_year": doc.ReleaseYear,
+			"status":       doc.Status,
+			"release_date": doc.ReleaseDate,
+			"genres":       doc.Genres,
+			"version":      doc.Version,
+		},
+		"$setOnInsert": bson.M{
+			"songs":             []document.SongInAlbum{},
+			"total_duration_ms": int64(0),
+			"rating_average":    float64(0),
+			"rating_count":      0,
+		},
+		"$currentDate": bson.M{"updated_at": true},
+	}
+	_, err := m.db.Collection(albumsCollectionName).
+		UpdateOne(ctx, newerVersion(doc.ID, doc.Version), update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		current, err := m.atVersion(ctx, albumsCollectionName, doc.ID, doc.Version)
+		if err != nil || !current {
+			return false, err
+		}
+	} else if err != nil {
+		return false, err
+	}
+
+	_, err = m.db.Collection(songCollectionName).UpdateMany(ctx,
+		bson.M{"album._id": doc.ID},
+		bson.M{
+			"$set": bson.M{
+				"album.status":       doc.Status,
+				"album.release_date": doc.ReleaseDate,
+			},
+			"$currentDate": bson.M{"updated_at": true},
+		})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
 
-func (m Mongo) CreateSong(ctx context.Context, song song.Song) error {
-	doc := document.NewSongFromDomain(song)
-	_, err := m.db.Collection(songCollectionName).InsertOne(ctx, doc)
+func (m Mongo) CreateSong(ctx context.Context, s song.Song) error {
+	doc := document.NewSongFromDomain(s)
+	update := bson.M{
+		"$set": bson.M{
+			"track_number": doc.TrackNumber,
+			"disc_number":  doc.DiscNumber,
+			"title":        doc.Title,
+			"duration_ms":  doc.DurationMs,
+			"isrc":         doc.ISRC,
+			"explicit":     doc.Explicit,
+			"composers":    doc.Composers,
+			"album":        doc.Album,
+			"artist":       doc.Artist,
+			"featuring":    doc.Featuring,
+			"version":      doc.Version,
+		},
+		"$setOnInsert": bson.M{
+			"plays": 0,
+			"likes": 0,
+		},
+		"$currentDate": bson.M{"updated_at": true},
+	}
+	_, err := m.db.Collection(songCollectionName).
+		UpdateOne(ctx, newerVersion(doc.ID, doc.Version), update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
 	return err
 }
 
-func (m Mongo) AddSongToAlbum(ctx context.Context, song song.Song) error {
-	result := m.db.Collection(albumsCollectionName).FindOne(ctx, bson.M{"_id": song.Album.ID})
-	if err := result.Err(); err != nil {
+func (m Mongo) AddSongToAlbum(ctx context.Context, s song.Song) error {
+	doc := document.NewSongInAlbumFromDomain(s)
+	filter := bson.M{"_id": s.Album.ID, "songs._id": bson.M{"$ne": doc.ID}}
+	update := bson.M{
+		"$push":        bson.M{"songs": doc},
+		"$inc":         bson.M{"total_duration_ms": doc.DurationMs},
+		"$currentDate": bson.M{"updated_at": true},
+	}
+	result, err := m.db.Collection(albumsCollectionName).UpdateOne(ctx, filter, update)
+	if err != nil || result.MatchedCount > 0 {
 		return err
 	}
 
-	doc := document.NewSongInAlbumFromDomain(song)
-	_, err := m.db.Collection(albumsCollectionName).
-		UpdateOne(ctx, bson.M{"_id": song.Album.ID}, bson.M{"$push": bson.M{"songs": doc}})
-	return err
+	return m.exists(ctx, albumsCollectionName, s.Album.ID)
 }
 
 func (m Mongo) GetSongByID(ctx context.Context, id string) (song.Song, error) {
-	result := m.db.Collection(songCollectionName).FindOne(ctx, bson.M{"_id": id})
+	result := m.db.Collection(songCollectionName).
+		FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(songProjection))
 	if err := result.Err(); err != nil {
-		return song.Song{}, err
+		return song.Song{}, mongoErr(err)
 	}
 
 	var doc document.Song
@@ -73,7 +173,7 @@ func (m Mongo) GetSongByID(ctx context.Context, id string) (song.Song, error) {
 func (m Mongo) GetArtistByID(ctx context.Context, id string) (song.Artist, error) {
 	result := m.db.Collection(artistCollectionName).FindOne(ctx, bson.M{"_id": id})
 	if err := result.Err(); err != nil {
-		return song.Artist{}, err
+		return song.Artist{}, mongoErr(err)
 	}
 
 	var doc document.Artist
@@ -87,7 +187,7 @@ func (m Mongo) GetArtistByID(ctx context.Context, id string) (song.Artist, error
 func (m Mongo) GetAlbumByID(ctx context.Context, id string) (song.Album, error) {
 	result := m.db.Collection(albumsCollectionName).FindOne(ctx, bson.M{"_id": id})
 	if err := result.Err(); err != nil {
-		return song.Album{}, err
+		return song.Album{}, mongoErr(err)
 	}
 
 	var doc document.Album
@@ -99,7 +199,10 @@ func (m Mongo) GetAlbumByID(ctx context.Context, id string) (song.Album, error)
 }
 
 func (m Mongo) GetAlbumsByArtistID(ctx context.Context, artistID string) ([]song.Album, error) {
-	cursor, err := m.db.Collection(albumsCollectionName).Find(ctx, bson.M{"artist._id": artistID})
+	cursor, err := m.db.Collection(albumsCollectionName).Find(ctx, bson.M{
+		"artist._id": artistID,
+		"status":     bson.M{"$nin": unreleasedStatuses},
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -118,7 +221,777 @@ func (m Mongo) GetAlbumsByArtistID(ctx context.Context, artistID string) ([]song
 
 func (m Mongo) IncrementSongPlays(ctx context.Context, songID string) error {
 	filter := bson.M{"_id": songID}
-	update := bson.M{"$inc": bson.M{"plays": 1}}
-	_, err := m.db.Collection(songCollectionName).UpdateOne(ctx, filter, update)
+	update := bson.M{
+		"$inc":         bson.M{"plays": 1},
+		"$currentDate": bson.M{"updated_at": true},
+	}
+	result, err := m.db.Collection(songCollectionName).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: song %s", song.NotFoundErr, songID)
+	}
+	return nil
+}
+
+// AddSongPlays counts each play id once: the ids of the last recentPlaysLimit
+// plays of a song are kept on it, so a redelivered play is skipped in the
+// same atomic update that counts it. Song reads project the ids out. It
+// returns the ids of songs not projected yet.
+func (m Mongo) AddSongPlays(ctx context.Context, plays map[string][]string) ([]string, error) {
+	if len(plays) == 0 {
+		return nil, nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(plays))
+	ids := make([]string, 0, len(plays))
+	for songID, playIDs := range plays {
+		ids = append(ids, songID)
+		for _, playID := range playIDs {
+			filter := bson.M{"_id": songID}
+			update := bson.M{
+				"$inc":         bson.M{"plays": 1},
+				"$currentDate": bson.M{"updated_at": true},
+			}
+			// plays published before they carried an id can't be told apart
+			if playID != "" {
+				filter["recent_plays"] = bson.M{"$ne": playID}
+				update["$push"] = bson.M{"recent_plays": bson.M{"$each": bson.A{playID}, "$slice": -recentPlaysLimit}}
+			}
+
+			models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update))
+		}
+	}
+
+	result, err := m.db.Collection(songCollectionName).
+		BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return nil, err
+	}
+
+	if result.MatchedCount == int64(len(models)) {
+		return nil, nil
+	}
+
+	// unmatched plays were either applied already or wait for their song
+	cursor, err := m.db.Collection(songCollectionName).Find(ctx,
+		bson.M{"_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var found []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	projected := make(map[string]bool, len(found))
+	for _, doc := range found {
+		projected[doc.ID] = true
+	}
+
+	missing := make([]string, 0, len(ids)-len(found))
+	for _, songID := range ids {
+		if !projected[songID] {
+			missing = append(missing, songID)
+		}
+	}
+	return missing, nil
+}
+
+func (m Mongo) CreateListener(ctx context.Context, listener song.Listener) error {
+	doc := document.NewListenerFromDomain(listener)
+	_, err := m.db.Collection(listenersCollectionName).
+		ReplaceOne(ctx, bson.M{"_id": doc.ID}, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m Mongo) AddListening(ctx context.Context, listening song.Listening) error {
+	doc := document.NewListeningFromDomain(listening)
+	_, err := m.db.Collection(listeningHistoryCollectionName).
+		ReplaceOne(ctx, bson.M{"_id": doc.ID}, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m Mongo) GetListeningHistory(ctx context.Context, listenerID string, offset, limit int) ([]song.Listening, int64, error) {
+	filter := bson.M{"listener_id": listenerID}
+	total, err := m.db.Collection(listeningHistoryCollectionName).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "played_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	cursor, err := m.db.Collection(listeningHistoryCollectionName).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var docs []document.Listening
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, 0, err
+	}
+
+	output := make([]song.Listening, len(docs), len(docs))
+	for i, doc := range docs {
+		output[i] = doc.ToDomain()
+	}
+	return output, total, nil
+}
+
+func (m Mongo) GetSongsByIDs(ctx context.Context, ids []string) ([]song.Song, error) {
+	cursor, err := m.db.Collection(songCollectionName).
+		Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(songProjection))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []document.Song
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	output := make([]song.Song, len(docs), len(docs))
+	for i, doc := range docs {
+		output[i] = doc.ToDomain()
+	}
+	return output, nil
+}
+
+func (m Mongo) SavePlaylist(ctx context.Context, playlist song.Playlist) error {
+	doc := document.NewPlaylistFromDomain(playlist)
+	filter := bson.M{"_id": doc.ID, "version": bson.M{"$lt": doc.Version}}
+	_, err := m.db.Collection(playlistsCollectionName).
+		ReplaceOne(ctx, filter, doc, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (m Mongo) GetPlaylistByID(ctx context.Context, id string) (song.Playlist, error) {
+	result := m.db.Collection(playlistsCollectionName).FindOne(ctx, bson.M{"_id": id})
+	if err := result.Err(); err != nil {
+		return song.Playlist{}, mongoErr(err)
+	}
+
+	var doc document.Playlist
+	if err := result.Decode(&doc); err != nil {
+		return song.Playlist{}, err
+	}
+
+	return doc.ToDomain(), nil
+}
+
+func (m Mongo) CreateFollow(ctx context.Context, follow song.Follow) error {
+	doc := document.NewFollowFromDomain(follow)
+	_, err := m.db.Collection(followsCollectionName).
+		ReplaceOne(ctx, bson.M{"_id": doc.ID}, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m Mongo) GetFollowerIDs(ctx context.Context, artistID string) ([]string, error) {
+	cursor, err := m.db.Collection(followsCollectionName).Find(ctx, bson.M{"artist_id": artistID})
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	var ids []string
+	for cursor.Next(ctx) {
+		var doc document.Follow
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, doc.ListenerID)
+	}
+
+	return ids, cursor.Err()
+}
+
+func (m Mongo) AddReleaseToFeeds(ctx context.Context, listenerIDs []string, album song.Album, publishedAt time.Time) error {
+	if len(listenerIDs) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, len(listenerIDs), len(listenerIDs))
+	for i, listenerID := range listenerIDs {
+		doc := document.NewReleaseFromDomain(song.Release{
+			ListenerID:  listenerID,
+			Album:       album,
+			PublishedAt: publishedAt,
+		})
+		models[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": doc.ID}).
+			SetReplacement(doc).
+			SetUpsert(true)
+	}
+
+	_, err := m.db.Collection(feedCollectionName).
+		BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (m Mongo) GetFeed(ctx context.Context, listenerID string, offset, limit int) ([]song.Release, int64, error) {
+	filter := bson.M{"listener_id": listenerID}
+	total, err := m.db.Collection(feedCollectionName).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "published_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	cursor, err := m.db.Collection(feedCollectionName).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var docs []document.Release
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, 0, err
+	}
+
+	output := make([]song.Release, len(docs), len(docs))
+	for i, doc := range docs {
+		output[i] = doc.ToDomain()
+	}
+	return output, total, nil
+}
+
+func (m Mongo) AddLike(ctx context.Context, like song.Like) error {
+	doc := document.NewLikeFromDomain(like)
+	_, err := m.db.Collection(likesCollectionName).
+		ReplaceOne(ctx, bson.M{"_id": doc.ID}, doc, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	likes, err := m.db.Collection(likesCollectionName).CountDocuments(ctx, bson.M{"song._id": like.Song.ID})
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": like.Song.ID}
+	update := bson.M{
+		"$set":         bson.M{"likes": likes},
+		"$currentDate": bson.M{"updated_at": true},
+	}
+	_, err = m.db.Collection(songCollectionName).UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (m Mongo) GetLikes(ctx context.Context, listenerID string, offset, limit int) ([]song.Like, int64, error) {
+	filter := bson.M{"listener_id": listenerID}
+	total, err := m.db.Collection(likesCollectionName).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "liked_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	cursor, err := m.db.Collection(likesCollectionName).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var docs []document.Like
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, 0, err
+	}
+
+	output := make([]song.Like, len(docs), len(docs))
+	for i, doc := range docs {
+		output[i] = doc.ToDomain()
+	}
+	return output, total, nil
+}
+
+func (m Mongo) AddAlbumRating(ctx context.Context, rating song.AlbumRating) error {
+	doc := document.NewAlbumRatingFromDomain(rating)
+	_, err := m.db.Collection(ratingsCollectionName).
+		ReplaceOne(ctx, bson.M{"_id": doc.ID}, doc, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"album_id": rating.AlbumID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     nil,
+			"average": bson.M{"$avg": "$rating"},
+			"count":   bson.M{"$sum": 1},
+		}}},
+	}
+	cursor, err := m.db.Collection(ratingsCollectionName).Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+
+	var stats []struct {
+		Average float64 `bson:"average"`
+		Count   int     `bson:"count"`
+	}
+	if err := cursor.All(ctx, &stats); err != nil {
+		return err
+	}
+
+	if len(stats) == 0 {
+		return nil
+	}
+
+	filter := bson.M{"_id": rating.AlbumID}
+	update := bson.M{
+		"$set": bson.M{
+			"rating_average": stats[0].Average,
+			"rating_count":   stats[0].Count,
+		},
+		"$currentDate": bson.M{"updated_at": true},
+	}
+	result, err := m.db.Collection(albumsCollectionName).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: album %s", song.NotFoundErr, rating.AlbumID)
+	}
+	return nil
+}
+
+func (m Mongo) GetAlbumsByGenre(ctx context.Context, genre song.Genre, offset, limit int) ([]song.Album, int64, error) {
+	filter := bson.M{
+		"genres": string(genre),
+		"status": bson.M{"$nin": unreleasedStatuses},
+	}
+	total, err := m.db.Collection(albumsCollectionName).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "title", Value: 1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	cursor, err := m.db.Collection(albumsCollectionName).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var docs []document.Album
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, 0, err
+	}
+
+	output := make([]song.Album, len(docs), len(docs))
+	for i, doc := range docs {
+		output[i] = doc.ToDomain()
+	}
+	return output, total, nil
+}
+
+func (m Mongo) GetArtistsByGenre(ctx context.Context, genre song.Genre, offset, limit int) ([]song.Artist, int64, error) {
+	filter := bson.M{"genres": string(genre)}
+	total, err := m.db.Collection(artistCollectionName).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "name", Value: 1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	cursor, err := m.db.Collection(artistCollectionName).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var docs []document.Artist
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, 0, err
+	}
+
+	output := make([]song.Artist, len(docs), len(docs))
+	for i, doc := range docs {
+		output[i] = doc.ToDomain()
+	}
+	return output, total, nil
+}
+
+func (m Mongo) GetSongsFeaturingArtist(ctx context.Context, artistID string) ([]song.Song, error) {
+	cursor, err := m.db.Collection(songCollectionName).Find(ctx, bson.M{
+		"featuring.artist._id": artistID,
+		"album.status":         bson.M{"$nin": unreleasedStatuses},
+	}, options.Find().SetProjection(songProjection))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []document.Song
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	output := make([]song.Song, len(docs), len(docs))
+	for i, doc := range docs {
+		output[i] = doc.ToDomain()
+	}
+	return output, nil
+}
+
+// MergeArtists rewrites every embedded copy of the source artist with the
+// target, moves its follows over and leaves a redirect behind. Each step is
+// idempotent so a redelivered merge converges to the same state.
+func (m Mongo) MergeArtists(ctx context.Context, merge song.ArtistMerge) error {
+	source := merge.SourceID
+	target := document.NewArtistFromDomain(merge.Target)
+	updates := []struct {
+		collection   string
+		filter       bson.M
+		update       bson.M
+		arrayFilters []interface{}
+	}{
+		{
+			collection: albumsCollectionName,
+			filter:     bson.M{"artist._id": source},
+			update:     bson.M{"$set": bson.M{"artist": target}, "$inc": bson.M{"version": 1}},
+		},
+		{
+			collection: songCollectionName,
+			filter:     bson.M{"artist._id": source},
+			update:     bson.M{"$set": bson.M{"artist": target}, "$inc": bson.M{"version": 1}},
+		},
+		{
+			collection:   albumsCollectionName,
+			filter:       bson.M{"songs.featuring.artist._id": source},
+			update:       bson.M{"$pull": bson.M{"songs.$[s].featuring": bson.M{"artist._id": source}}},
+			arrayFilters: []interface{}{bson.M{"s.featuring.artist._id": target.ID}},
+		},
+		{
+			collection:   albumsCollectionName,
+			filter:       bson.M{"songs.featuring.artist._id": source},
+			update:       bson.M{"$set": bson.M{"songs.$[].featuring.$[f].artist": target}},
+			arrayFilters: []interface{}{bson.M{"f.artist._id": source}},
+		},
+		{
+			collection: albumsCollectionName,
+			filter:     bson.M{"artist._id": target.ID},
+			update:     bson.M{"$pull": bson.M{"songs.$[].featuring": bson.M{"artist._id": target.ID}}},
+		},
+		{
+			collection: songCollectionName,
+			filter:     bson.M{"featuring.artist._id": bson.M{"$all": []string{source, target.ID}}},
+			update:     bson.M{"$pull": bson.M{"featuring": bson.M{"artist._id": source}}},
+		},
+		{
+			collection:   songCollectionName,
+			filter:       bson.M{"featuring.artist._id": source},
+			update:       bson.M{"$set": bson.M{"featuring.$[f].artist": target}},
+			arrayFilters: []interface{}{bson.M{"f.artist._id": source}},
+		},
+		{
+			collection: songCollectionName,
+			filter:     bson.M{"artist._id": target.ID},
+			update:     bson.M{"$pull": bson.M{"featuring": bson.M{"artist._id": target.ID}}},
+		},
+		{
+			collection:   playlistsCollectionName,
+			filter:       bson.M{"songs.artist._id": source},
+			update:       bson.M{"$set": bson.M{"songs.$[s].artist": target}},
+			arrayFilters: []interface{}{bson.M{"s.artist._id": source}},
+		},
+		{collection: listeningHistoryCollectionName, filter: bson.M{"song.artist._id": source}, update: bson.M{"$set": bson.M{"song.artist": target}}},
+		{collection: likesCollectionName, filter: bson.M{"song.artist._id": source}, update: bson.M{"$set": bson.M{"song.artist": target}}},
+		{collection: feedCollectionName, filter: bson.M{"artist._id": source}, update: bson.M{"$set": bson.M{"artist": target}}},
+		{collection: redirectsCollectionName, filter: bson.M{"artist_id": source}, update: bson.M{"$set": bson.M{"artist_id": target.ID}}},
+	}
+	for _, u := range updates {
+		if u.collection == albumsCollectionName || u.collection == songCollectionName {
+			u.update["$currentDate"] = bson.M{"updated_at": true}
+		}
+
+		opts := options.Update()
+		if u.arrayFilters != nil {
+			opts.SetArrayFilters(options.ArrayFilters{Filters: u.arrayFilters})
+		}
+
+		if _, err := m.db.Collection(u.collection).UpdateMany(ctx, u.filter, u.update, opts); err != nil {
+			return fmt.Errorf("%s: %w", u.collection, err)
+		}
+	}
+
+	if err := m.moveFollows(ctx, source, target.ID); err != nil {
+		return err
+	}
+
+	redirect := document.ArtistRedirect{
+		ID:       source,
+		ArtistID: target.ID,
+		MergedAt: merge.MergedAt,
+	}
+	_, err := m.db.Collection(redirectsCollectionName).
+		ReplaceOne(ctx, bson.M{"_id": redirect.ID}, redirect, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	target.UpdatedAt = time.Now()
+	_, err = m.db.Collection(artistCollectionName).
+		ReplaceOne(ctx, bson.M{"_id": target.ID}, target, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	_, err = m.db.Collection(artistCollectionName).DeleteOne(ctx, bson.M{"_id": source})
+	return err
+}
+
+func (m Mongo) moveFollows(ctx context.Context, sourceID, targetID string) error {
+	cursor, err := m.db.Collection(followsCollectionName).Find(ctx, bson.M{"artist_id": sourceID})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	var models []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var doc document.Follow
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+
+		moved := document.NewFollowFromDomain(song.Follow{
+			ListenerID: doc.ListenerID,
+			ArtistID:   targetID,
+			FollowedAt: doc.FollowedAt,
+		})
+		models = append(models,
+			mongo.NewInsertOneModel().SetDocument(moved),
+			mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": doc.ID}),
+		)
+	}
+
+	if err := cursor.Err(); err != nil || len(models) == 0 {
+		return err
+	}
+
+	_, err = m.db.Collection(followsCollectionName).
+		BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (m Mongo) GetArtistRedirect(ctx context.Context, id string) (string, error) {
+	result := m.db.Collection(redirectsCollectionName).FindOne(ctx, bson.M{"_id": id})
+	if err := result.Err(); err != nil {
+		return "", mongoErr(err)
+	}
+
+	var doc document.ArtistRedirect
+	if err := result.Decode(&doc); err != nil {
+		return "", err
+	}
+
+	return doc.ArtistID, nil
+}
+
+func (m Mongo) RecordCheckpoint(ctx context.Context, projection string, eventAt, processedAt time.Time, processed int64) error {
+	update := bson.M{
+		"$max": bson.M{"last_event_at": eventAt},
+		"$set": bson.M{"last_processed_at": processedAt},
+		"$inc": bson.M{"processed": processed},
+	}
+	_, err := m.db.Collection(checkpointsCollectionName).
+		UpdateOne(ctx, bson.M{"_id": projection}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (m Mongo) RecordProjectionError(ctx context.Context, projection, message string, failedAt time.Time) error {
+	update := bson.M{"$set": bson.M{"last_error": message, "last_error_at": failedAt}}
+	_, err := m.db.Collection(checkpointsCollectionName).
+		UpdateOne(ctx, bson.M{"_id": projection}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (m Mongo) RecordProjectionBacklog(ctx context.Context, projection string, pending int, sampledAt time.Time) error {
+	set := bson.M{"pending": pending, "sampled_at": sampledAt}
+	if pending == 0 {
+		set["drained_at"] = sampledAt
+	}
+
+	update := bson.M{"$set": set}
+	_, err := m.db.Collection(checkpointsCollectionName).
+		UpdateOne(ctx, bson.M{"_id": projection}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (m Mongo) GetCheckpoints(ctx context.Context) ([]song.Checkpoint, error) {
+	cursor, err := m.db.Collection(checkpointsCollectionName).
+		Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []document.Checkpoint
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	output := make([]song.Checkpoint, len(docs), len(docs))
+	for i, doc := range docs {
+		output[i] = doc.ToDomain()
+	}
+	return output, nil
+}
+
+func (m Mongo) ParkEvent(ctx context.Context, parked song.ParkedEvent) error {
+	doc := document.NewParkedEventFromDomain(parked)
+	_, err := m.db.Collection(parkedEventsCollectionName).
+		ReplaceOne(ctx, bson.M{"_id": doc.ID}, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m Mongo) GetParkedEvents(ctx context.Context, dependency string) ([]song.ParkedEvent, error) {
+	cursor, err := m.db.Collection(parkedEventsCollectionName).
+		Find(ctx, bson.M{"dependency": dependency}, options.Find().SetSort(bson.M{"parked_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []document.ParkedEvent
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	output := make([]song.ParkedEvent, len(docs), len(docs))
+	for i, doc := range docs {
+		output[i] = doc.ToDomain()
+	}
+	return output, nil
+}
+
+// DeleteParkedEvent reports whether this call removed the event.
+func (m Mongo) DeleteParkedEvent(ctx context.Context, id string) (bool, error) {
+	result, err := m.db.Collection(parkedEventsCollectionName).DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount == 1, nil
+}
+
+func (m Mongo) WalkArtists(ctx context.Context, fn func(song.Artist) error) error {
+	return walk(ctx, m.db.Collection(artistCollectionName), bson.M{}, nil, func(doc document.Artist) error {
+		return fn(doc.ToDomain())
+	})
+}
+
+func (m Mongo) WalkAlbums(ctx context.Context, fn func(song.Album) error) error {
+	opts := options.Find().SetProjection(bson.M{"songs": 0})
+	filter := bson.M{"status": bson.M{"$nin": unreleasedStatuses}}
+	return walk(ctx, m.db.Collection(albumsCollectionName), filter, opts, func(doc document.Album) error {
+		return fn(doc.ToDomain())
+	})
+}
+
+func (m Mongo) WalkSongs(ctx context.Context, fn func(song.Song) error) error {
+	opts := options.Find().SetProjection(songProjection)
+	filter := bson.M{"album.status": bson.M{"$nin": unreleasedStatuses}}
+	return walk(ctx, m.db.Collection(songCollectionName), filter, opts, func(doc document.Song) error {
+		return fn(doc.ToDomain())
+	})
+}
+
+// walk streams the documents of collection matching filter in _id order,
+// decoding one at a time so memory stays flat however large it is.
+func walk[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, opts *options.FindOptions, fn func(T) error) error {
+	if opts == nil {
+		opts = options.Find()
+	}
+	opts.SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	for cursor.Next(ctx) {
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+func (m Mongo) exists(ctx context.Context, collection, id string) error {
+	count, err := m.db.Collection(collection).CountDocuments(ctx, bson.M{"_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf("%w: %s %s", song.NotFoundErr, collection, id)
+	}
+	return nil
+}
+
+// atVersion reports whether the document is stored at exactly version.
+func (m Mongo) atVersion(ctx context.Context, collection, id string, version int) (bool, error) {
+	count, err := m.db.Collection(collection).
+		CountDocuments(ctx, bson.M{"_id": id, "version": version}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// newerVersion makes an upsert of a stale version fail on the duplicate id.
+func newerVersion(id string, version int) bson.M {
+	return bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"version": bson.M{"$lt": version}},
+			bson.M{"version": bson.M{"$exists": false}},
+		},
+	}
+}
+
+func mongoErr(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("%w: %w", song.NotFoundErr, err)
+	}
+
 	return err
 }
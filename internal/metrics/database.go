@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	mongoevent "go.mongodb.org/mongo-driver/event"
+	"gorm.io/gorm"
+	"time"
+)
+
+const startedAtKey = "metrics:started_at"
+
+var (
+	dbDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_call_duration_seconds",
+		Help:    "Time spent in database calls, by system, operation and outcome.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"system", "operation", "outcome"})
+)
+
+type (
+	// GormPlugin times every create, query, update, delete, row and raw call
+	// made through a gorm.DB, such as the one behind database.Gorm.
+	GormPlugin struct{}
+)
+
+func (GormPlugin) Name() string {
+	return "metrics"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	create, query, update := callbacks.Create(), callbacks.Query(), callbacks.Update()
+	del, row, raw := callbacks.Delete(), callbacks.Row(), callbacks.Raw()
+
+	return errors.Join(
+		create.Before("gorm:create").Register("metrics:before_create", startTimer),
+		create.After("gorm:create").Register("metrics:after_create", observe("create")),
+		query.Before("gorm:query").Register("metrics:before_query", startTimer),
+		query.After("gorm:query").Register("metrics:after_query", observe("query")),
+		update.Before("gorm:update").Register("metrics:before_update", startTimer),
+		update.After("gorm:update").Register("metrics:after_update", observe("update")),
+		del.Before("gorm:delete").Register("metrics:before_delete", startTimer),
+		del.After("gorm:delete").Register("metrics:after_delete", observe("delete")),
+		row.Before("gorm:row").Register("metrics:before_row", startTimer),
+		row.After("gorm:row").Register("metrics:after_row", observe("row")),
+		raw.Before("gorm:raw").Register("metrics:before_raw", startTimer),
+		raw.After("gorm:raw").Register("metrics:after_raw", observe("raw")),
+	)
+}
+
+func startTimer(tx *gorm.DB) {
+	tx.InstanceSet(startedAtKey, time.Now())
+}
+
+func observe(operation string) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		v, ok := tx.InstanceGet(startedAtKey)
+		if !ok {
+			return
+		}
+
+		startedAt, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+
+		err := tx.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+
+		dbDuration.WithLabelValues("postgres", operation, outcome(err)).Observe(time.Since(startedAt).Seconds())
+	}
+}
+
+// MongoMonitor times every command sent by a mongo.Client, such as the one
+// behind database.Mongo.
+func MongoMonitor() *mongoevent.CommandMonitor {
+	return &mongoevent.CommandMonitor{
+		Succeeded: func(_ context.Context, e *mongoevent.CommandSucceededEvent) {
+			dbDuration.WithLabelValues("mongo", e.CommandName, "ok").Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *mongoevent.CommandFailedEvent) {
+			dbDuration.WithLabelValues("mongo", e.CommandName, "error").Observe(e.Duration.Seconds())
+		},
+	}
+}
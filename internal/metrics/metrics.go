@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"context"
+	"cqrs-sample/pkg/event"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests served, by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time spent serving HTTP requests, by route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	eventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_published_total",
+		Help: "Events published to the broker, by event and outcome.",
+	}, []string{"event", "outcome"})
+
+	messagesHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "queue_messages_handled_total",
+		Help: "Messages handled by the worker, by queue and outcome.",
+	}, []string{"queue", "outcome"})
+
+	handlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "queue_handler_duration_seconds",
+		Help:    "Time spent handling a message, by queue and outcome.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"queue", "outcome"})
+
+	queuePending = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "queue_pending_messages",
+		Help: "Messages waiting in a projection queue when last sampled.",
+	}, []string{"queue"})
+)
+
+type (
+	Publisher interface {
+		Publish(ctx context.Context, ev event.Message, key event.Event) error
+	}
+
+	BacklogDatabase interface {
+		RecordProjectionBacklog(ctx context.Context, projection string, pending int, sampledAt time.Time) error
+	}
+
+	// InstrumentedPublisher counts the events published through next.
+	InstrumentedPublisher struct {
+		next Publisher
+	}
+
+	// InstrumentedBacklog exposes the sampled backlog of every projection
+	// queue before storing it in next.
+	InstrumentedBacklog struct {
+		next BacklogDatabase
+	}
+
+	// HandlerRecorder implements queue.MetricsRecorder.
+	HandlerRecorder struct{}
+)
+
+func NewInstrumentedPublisher(next Publisher) *InstrumentedPublisher {
+	return &InstrumentedPublisher{
+		next: next,
+	}
+}
+
+func NewInstrumentedBacklog(next BacklogDatabase) *InstrumentedBacklog {
+	return &InstrumentedBacklog{
+		next: next,
+	}
+}
+
+// Requests records the rate, errors and duration of HTTP requests per chi
+// route pattern, keeping label cardinality bounded by the routes declared.
+func Requests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		startedAt := time.Now()
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(route, r.Method).Observe(time.Since(startedAt).Seconds())
+	})
+}
+
+func (p InstrumentedPublisher) Publish(ctx context.Context, ev event.Message, key event.Event) error {
+	err := p.next.Publish(ctx, ev, key)
+	eventsPublished.WithLabelValues(string(key), outcome(err)).Inc()
+	return err
+}
+
+func (b InstrumentedBacklog) RecordProjectionBacklog(ctx context.Context, projection string, pending int, sampledAt time.Time) error {
+	queuePending.WithLabelValues(projection).Set(float64(pending))
+	return b.next.RecordProjectionBacklog(ctx, projection, pending, sampledAt)
+}
+
+func (HandlerRecorder) RecordHandled(queue string, duration time.Duration, err error) {
+	messagesHandled.WithLabelValues(queue, outcome(err)).Inc()
+	handlerDuration.WithLabelValues(queue, outcome(err)).Observe(duration.Seconds())
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "ok"
+}
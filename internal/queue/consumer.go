@@ -0,0 +1,186 @@
+package queue
+
+import (
+	"context"
+	"cqrs-sample/pkg/event"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"hash/fnv"
+	"sync"
+)
+
+type (
+	// ConsumerConfig tunes how a queue is consumed. Prefetch caps the
+	// messages in flight, delivered but not yet acknowledged, and defaults to
+	// twice the concurrency. Concurrency is the number of messages handled
+	// in parallel. Ordered routes messages by aggregate ID so each aggregate
+	// is handled by a single goroutine in the order it was queued; otherwise
+	// any idle goroutine takes the next message. The order only holds within
+	// one consumer: several workers sharing a queue need a consistent-hash
+	// exchange in front of per-worker queues.
+	ConsumerConfig struct {
+		Prefetch    int
+		Concurrency int
+		Ordered     bool
+	}
+
+	ackChannel interface {
+		Ack(tag uint64, multiple bool) error
+		Nack(tag uint64, multiple bool, requeue bool) error
+	}
+
+	// acknowledger acknowledges deliveries in the order they arrived even
+	// when handlers finish out of order, so a single multiple ack covers
+	// every message up to the first one still in flight.
+	acknowledger struct {
+		mu      sync.Mutex
+		channel ackChannel
+		next    uint64
+		acked   uint64
+		done    map[uint64]bool
+	}
+)
+
+// SubscribeConcurrent consumes queue with a pool of cfg.Concurrency
+// goroutines, see ConsumerConfig for the ordering it keeps.
+//
+// Cancelling ctx stops the consumption; messages already delivered are still
+// handled and acknowledged before it returns.
+func (m RabbitMQSubscriber) SubscribeConcurrent(ctx context.Context, queue string, cfg ConsumerConfig, handler Handler) error {
+	concurrency := max(cfg.Concurrency, 1)
+	prefetch := cfg.Prefetch
+	if prefetch <= 0 {
+		prefetch = 2 * concurrency
+	}
+
+	channel, err := m.conn.Channel()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = channel.Close()
+	}()
+
+	if err := channel.Qos(prefetch, 0, false); err != nil {
+		return err
+	}
+
+	messages, err := channel.Consume(queue, queue, false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	stop := cancelOnDone(ctx, channel, queue)
+	defer stop()
+	handlerCtx := context.WithoutCancel(ctx)
+
+	ack := newAcknowledger(channel)
+	lanes := make([]chan amqp.Delivery, concurrency, concurrency)
+	shared := make(chan amqp.Delivery)
+	var wg sync.WaitGroup
+	for i := range lanes {
+		lanes[i] = shared
+		if cfg.Ordered {
+			lanes[i] = make(chan amqp.Delivery, prefetch)
+		}
+
+		wg.Add(1)
+		go func(lane <-chan amqp.Delivery) {
+			defer wg.Done()
+			for message := range lane {
+				ack.complete(message.DeliveryTag, handle(handlerCtx, queue, handler, message))
+			}
+		}(lanes[i])
+	}
+
+	for message := range messages {
+		ack.track(message.DeliveryTag)
+		lane := 0
+		if cfg.Ordered {
+			lane = partition(message, concurrency)
+		}
+
+		lanes[lane] <- message
+	}
+
+	close(shared)
+	if cfg.Ordered {
+		for _, lane := range lanes {
+			close(lane)
+		}
+	}
+
+	wg.Wait()
+	return closed(ctx)
+}
+
+func newAcknowledger(channel ackChannel) *acknowledger {
+	return &acknowledger{
+		channel: channel,
+		done:    make(map[uint64]bool),
+	}
+}
+
+// track records the first delivery tag of the channel; tags are sequential
+// from there on.
+func (a *acknowledger) track(tag uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.next == 0 {
+		a.next = tag
+		a.acked = tag - 1
+	}
+}
+
+// complete records the outcome of tag. A failed message is nacked with
+// requeue, and RabbitMQ redelivers it after the messages already handed out
+// behind it, including later ones of the same aggregate in an ordered lane;
+// handlers rely on the projection versions to skip what that reorders.
+func (a *acknowledger) complete(tag uint64, ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.done[tag] = ok
+	last := a.acked
+	for {
+		ok, finished := a.done[a.next]
+		if !finished {
+			break
+		}
+
+		delete(a.done, a.next)
+		if ok {
+			last = a.next
+		} else {
+			a.flush(last)
+			_ = a.channel.Nack(a.next, false, true)
+			last = a.next
+			a.acked = a.next
+		}
+
+		a.next++
+	}
+
+	a.flush(last)
+}
+
+func (a *acknowledger) flush(last uint64) {
+	if last > a.acked {
+		_ = a.channel.Ack(last, true)
+		a.acked = last
+	}
+}
+
+// partition hashes the aggregate ID header, falling back to the body for
+// messages published without one.
+func partition(message amqp.Delivery, partitions int) int {
+	h := fnv.New32a()
+	if id, ok := message.Headers[event.AggregateIDHeader].(string); ok && id != "" {
+		_, _ = h.Write([]byte(id))
+	} else {
+		_, _ = h.Write(message.Body)
+	}
+
+	return int(h.Sum32() % uint32(partitions))
+}
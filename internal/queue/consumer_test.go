@@ -0,0 +1,94 @@
+package queue
+
+import (
+	"cqrs-sample/pkg/event"
+	"fmt"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"reflect"
+	"testing"
+)
+
+type fakeAckChannel struct {
+	calls []string
+}
+
+func (f *fakeAckChannel) Ack(tag uint64, multiple bool) error {
+	f.calls = append(f.calls, fmt.Sprintf("ack %d multiple=%t", tag, multiple))
+	return nil
+}
+
+func (f *fakeAckChannel) Nack(tag uint64, multiple bool, requeue bool) error {
+	f.calls = append(f.calls, fmt.Sprintf("nack %d multiple=%t requeue=%t", tag, multiple, requeue))
+	return nil
+}
+
+func Test_Acknowledger_Acks_In_Delivery_Order(t *testing.T) {
+	// Arrange
+	channel := &fakeAckChannel{}
+	ack := newAcknowledger(channel)
+	for tag := uint64(1); tag <= 3; tag++ {
+		ack.track(tag)
+	}
+
+	// Act
+	ack.complete(3, true)
+	ack.complete(2, true)
+	beforeFirst := len(channel.calls)
+	ack.complete(1, true)
+
+	// Assert
+	if beforeFirst != 0 {
+		t.Errorf("acks before first completed: got = %v, want = []", channel.calls[:beforeFirst])
+	}
+
+	want := []string{"ack 3 multiple=true"}
+	if !reflect.DeepEqual(channel.calls, want) {
+		t.Errorf("calls: got = %v, want = %v", channel.calls, want)
+	}
+}
+
+func Test_Acknowledger_Nacks_Failed_Delivery_Between_Acks(t *testing.T) {
+	// Arrange
+	channel := &fakeAckChannel{}
+	ack := newAcknowledger(channel)
+	for tag := uint64(5); tag <= 8; tag++ {
+		ack.track(tag)
+	}
+
+	// Act
+	ack.complete(8, true)
+	ack.complete(6, false)
+	ack.complete(5, true)
+	ack.complete(7, true)
+
+	// Assert
+	want := []string{
+		"ack 5 multiple=true",
+		"nack 6 multiple=false requeue=true",
+		"ack 8 multiple=true",
+	}
+	if !reflect.DeepEqual(channel.calls, want) {
+		t.Errorf("calls: got = %v, want = %v", channel.calls, want)
+	}
+}
+
+func Test_Partition_Routes_Aggregate_To_One_Lane(t *testing.T) {
+	// Arrange
+	created := amqp.Delivery{Headers: amqp.Table{event.AggregateIDHeader: "album-1"}, Body: []byte(`{"v":1}`)}
+	published := amqp.Delivery{Headers: amqp.Table{event.AggregateIDHeader: "album-1"}, Body: []byte(`{"v":2}`)}
+	legacy := amqp.Delivery{Body: []byte(`{"v":1}`)}
+
+	// Act
+	createdLane := partition(created, 8)
+	publishedLane := partition(published, 8)
+	legacyLane := partition(legacy, 8)
+
+	// Assert
+	if createdLane != publishedLane {
+		t.Errorf("lanes of one aggregate: got = %d and %d, want equal", createdLane, publishedLane)
+	}
+
+	if again := partition(legacy, 8); legacyLane != again || legacyLane < 0 || legacyLane >= 8 {
+		t.Errorf("lane without aggregate id: got = %d and %d, want one lane in [0, 8)", legacyLane, again)
+	}
+}
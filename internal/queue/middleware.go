@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+var (
+	HandlerPanicErr = errors.New("handler panicked")
+)
+
+type (
+	// Middleware wraps a Handler the way chi middlewares wrap an
+	// http.Handler.
+	Middleware func(next Handler) Handler
+
+	// Middlewares is a chain applied in order, the first one outermost.
+	Middlewares []Middleware
+
+	HandlerFunc func(ctx context.Context, body []byte, headers map[string]interface{}) error
+
+	// MetricsRecorder receives the outcome of every message handled.
+	MetricsRecorder interface {
+		RecordHandled(queue string, duration time.Duration, err error)
+	}
+
+	queueKey struct{}
+)
+
+func (f HandlerFunc) Handle(ctx context.Context, body []byte, headers map[string]interface{}) error {
+	return f(ctx, body, headers)
+}
+
+func Chain(middlewares ...Middleware) Middlewares {
+	return middlewares
+}
+
+func (ms Middlewares) Handler(h Handler) Handler {
+	for i := len(ms) - 1; i >= 0; i-- {
+		h = ms[i](h)
+	}
+
+	return h
+}
+
+// QueueFromContext returns the queue the message being handled was consumed
+// from.
+func QueueFromContext(ctx context.Context) string {
+	queue, _ := ctx.Value(queueKey{}).(string)
+	return queue
+}
+
+func withQueue(ctx context.Context, queue string) context.Context {
+	return context.WithValue(ctx, queueKey{}, queue)
+}
+
+// Recoverer turns a panicking handler into a failed message, so the consumer
+// keeps running and the message is requeued.
+func Recoverer(next Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, body []byte, headers map[string]interface{}) (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				slog.ErrorContext(ctx, "handler panicked",
+					"queue", QueueFromContext(ctx),
+					"panic", rec,
+					"stack", string(debug.Stack()))
+				err = fmt.Errorf("%w: %v", HandlerPanicErr, rec)
+			}
+		}()
+
+		return next.Handle(ctx, body, headers)
+	})
+}
+
+// Logger logs every message handled with its queue, duration and error.
+func Logger(logger *slog.Logger) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, body []byte, headers map[string]interface{}) error {
+			startedAt := time.Now()
+			err := next.Handle(ctx, body, headers)
+			attrs := []any{
+				"queue", QueueFromContext(ctx),
+				"duration", time.Since(startedAt),
+			}
+			if err != nil {
+				logger.ErrorContext(ctx, "message failed", append(attrs, "error", err)...)
+			} else {
+				logger.DebugContext(ctx, "message handled", attrs...)
+			}
+
+			return err
+		})
+	}
+}
+
+// Timeout cancels the handler context after d; zero disables it.
+func Timeout(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		if d <= 0 {
+			return next
+		}
+
+		return HandlerFunc(func(ctx context.Context, body []byte, headers map[string]interface{}) error {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			return next.Handle(ctx, body, headers)
+		})
+	}
+}
+
+func Metrics(recorder MetricsRecorder) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, body []byte, headers map[string]interface{}) error {
+			startedAt := time.Now()
+			err := next.Handle(ctx, body, headers)
+			recorder.RecordHandled(QueueFromContext(ctx), time.Since(startedAt), err)
+			return err
+		})
+	}
+}
@@ -2,10 +2,16 @@ package queue
 
 import (
 	"context"
+	"cqrs-sample/internal/logging"
 	"cqrs-sample/pkg/event"
 	"errors"
-	"fmt"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"log/slog"
+	"time"
+)
+
+var (
+	ConsumerClosedErr = errors.New("consumer closed")
 )
 
 type (
@@ -37,6 +43,15 @@ func NewRabbitMQSubscriber(conn *amqp.Connection) *RabbitMQSubscriber {
 }
 
 func (p RabbitMQPublisher) Publish(ctx context.Context, message event.Message, e event.Event) error {
+	publishedAt := time.Now().UTC()
+	headers := amqp.Table{event.PublishedAtHeader: publishedAt}
+	if id := logging.RequestID(ctx); id != "" {
+		headers[event.RequestIDHeader] = id
+	}
+	for k, v := range message.Headers {
+		headers[k] = v
+	}
+
 	err := p.ch.PublishWithContext(ctx,
 		p.exchange,
 		string(e),
@@ -45,10 +60,16 @@ func (p RabbitMQPublisher) Publish(ctx context.Context, message event.Message, e
 		amqp.Publishing{
 			ContentType: "application/json",
 			Body:        message.Body,
-			Headers:     message.Headers,
+			Headers:     headers,
+			Timestamp:   publishedAt,
 		})
 
-	fmt.Println("message received at", p.exchange, e)
+	if err != nil {
+		slog.ErrorContext(ctx, "publish failed", "exchange", p.exchange, "event", e, "error", err)
+	} else {
+		slog.DebugContext(ctx, "message published", "exchange", p.exchange, "event", e)
+	}
+
 	return err
 }
 
@@ -62,9 +83,87 @@ func (m RabbitMQSubscriber) Subscribe(ctx context.Context, queue string, handler
 		_ = channel.Close()
 	}()
 
+	return m.consume(ctx, channel, queue, handler)
+}
+
+// Broadcast delivers every e published to exchange to this process through
+// an exclusive queue that is deleted when the connection closes, so each
+// running instance receives its own copy.
+func (m RabbitMQSubscriber) Broadcast(ctx context.Context, exchange string, e event.Event, handler Handler) error {
+	channel, err := m.conn.Channel()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = channel.Close()
+	}()
+
+	q, err := channel.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := channel.QueueBind(q.Name, string(e), exchange, false, nil); err != nil {
+		return err
+	}
+
+	return m.consume(ctx, channel, q.Name, handler)
+}
+
+// Declare makes sure exchange exists and routes every one of events to a
+// durable queue, so consumers can be added without provisioning the broker.
+func (m RabbitMQSubscriber) Declare(_ context.Context, exchange, queue string, events ...event.Event) error {
+	channel, err := m.conn.Channel()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = channel.Close()
+	}()
+
+	if err := channel.ExchangeDeclare(exchange, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return err
+	}
+
+	if _, err := channel.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		if err := channel.QueueBind(queue, string(e), exchange, false, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Pending returns how many messages are ready in queue, waiting for a
+// consumer.
+func (m RabbitMQSubscriber) Pending(_ context.Context, queue string) (int, error) {
+	channel, err := m.conn.Channel()
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		_ = channel.Close()
+	}()
+
+	q, err := channel.QueueDeclarePassive(queue, false, false, false, false, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.Messages, nil
+}
+
+func (m RabbitMQSubscriber) consume(ctx context.Context, channel *amqp.Channel, queue string, handler Handler) error {
 	messages, err := channel.Consume(
 		queue,
-		"",
+		queue,
 		false,
 		false,
 		false,
@@ -76,19 +175,60 @@ func (m RabbitMQSubscriber) Subscribe(ctx context.Context, queue string, handler
 		return err
 	}
 
+	stop := cancelOnDone(ctx, channel, queue)
+	defer stop()
+
+	handlerCtx := context.WithoutCancel(ctx)
 	for message := range messages {
-		fmt.Println("message received at", queue)
-		err := handler.Handle(ctx, message.Body, message.Headers)
-		if err != nil {
-			fmt.Println(err)
-		}
+		deliver(handlerCtx, queue, handler, message)
+	}
 
-		if err == nil || errors.Is(err, event.InvalidPayloadErr) {
-			_ = message.Ack(false)
-		} else {
-			_ = message.Nack(false, true)
+	return closed(ctx)
+}
+
+// cancelOnDone cancels the consumer tagged tag once ctx is done, letting the
+// messages already delivered drain before the delivery channel closes. The
+// returned func releases the watch when consumption ended on its own.
+func cancelOnDone(ctx context.Context, channel *amqp.Channel, tag string) func() {
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = channel.Cancel(tag, false)
+		case <-stop:
 		}
+	}()
+
+	return func() {
+		close(stop)
+	}
+}
+
+// closed tells a consumer stopped through ctx from one whose channel or
+// connection was closed underneath it.
+func closed(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return nil
 	}
 
-	return nil
+	return ConsumerClosedErr
+}
+
+func deliver(ctx context.Context, queue string, handler Handler, message amqp.Delivery) {
+	if handle(ctx, queue, handler, message) {
+		_ = message.Ack(false)
+	} else {
+		_ = message.Nack(false, true)
+	}
+}
+
+// handle reports whether message is done with and can be acknowledged,
+// rather than requeued for another attempt.
+func handle(ctx context.Context, queue string, handler Handler, message amqp.Delivery) bool {
+	if id, ok := message.Headers[event.RequestIDHeader].(string); ok {
+		ctx = logging.WithRequestID(ctx, id)
+	}
+
+	err := handler.Handle(withQueue(ctx, queue), message.Body, message.Headers)
+	return err == nil || errors.Is(err, event.InvalidPayloadErr)
 }
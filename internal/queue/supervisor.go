@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = time.Minute
+)
+
+var (
+	ShutdownTimeoutErr = errors.New("shutdown timed out")
+)
+
+type (
+	// Supervisor keeps long running tasks, such as subscriptions, alive until
+	// their context is cancelled: a task that returns early is restarted after
+	// an exponential backoff, reset once the task has run for maxBackoff.
+	// Restarts share whatever the task captured, so a task whose connection
+	// is gone keeps failing until its context is cancelled.
+	Supervisor struct {
+		minBackoff time.Duration
+		maxBackoff time.Duration
+		wg         sync.WaitGroup
+		now        func() time.Time
+		after      func(d time.Duration) <-chan time.Time
+	}
+)
+
+func NewSupervisor(minBackoff, maxBackoff time.Duration) *Supervisor {
+	if minBackoff <= 0 {
+		minBackoff = DefaultMinBackoff
+	}
+
+	if maxBackoff < minBackoff {
+		maxBackoff = max(DefaultMaxBackoff, minBackoff)
+	}
+
+	return &Supervisor{
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		now:        time.Now,
+		after:      time.After,
+	}
+}
+
+// Go runs task in its own goroutine until ctx is done.
+func (s *Supervisor) Go(ctx context.Context, name string, task func(ctx context.Context) error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		backoff := s.minBackoff
+		for {
+			startedAt := s.now()
+			err := task(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+
+			if s.now().Sub(startedAt) >= s.maxBackoff {
+				backoff = s.minBackoff
+			}
+
+			slog.ErrorContext(ctx, "task stopped", "task", name, "error", err, "restart_in", backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.after(backoff):
+			}
+
+			backoff = min(2*backoff, s.maxBackoff)
+		}
+	}()
+}
+
+// Wait blocks until every task returned after its context was cancelled, or
+// fails with ShutdownTimeoutErr when they take longer than timeout.
+func (s *Supervisor) Wait(timeout time.Duration) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	if timeout <= 0 {
+		<-done
+		return nil
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return ShutdownTimeoutErr
+	}
+}
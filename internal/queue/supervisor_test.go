@@ -0,0 +1,141 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var taskErr = errors.New("task failed")
+
+func immediately(waits *[]time.Duration) func(d time.Duration) <-chan time.Time {
+	return func(d time.Duration) <-chan time.Time {
+		*waits = append(*waits, d)
+		ch := make(chan time.Time, 1)
+		ch <- time.Time{}
+		return ch
+	}
+}
+
+func Test_Supervisor_Restarts_With_Exponential_Backoff(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var waits []time.Duration
+	supervisor := NewSupervisor(time.Second, 4*time.Second)
+	supervisor.after = immediately(&waits)
+	calls := 0
+
+	// Act
+	supervisor.Go(ctx, "task", func(ctx context.Context) error {
+		calls++
+		if calls == 5 {
+			cancel()
+		}
+
+		return taskErr
+	})
+	err := supervisor.Wait(time.Second)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("err: got = %v, want = nil", err)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+	if !reflect.DeepEqual(waits, want) {
+		t.Errorf("waits: got = %v, want = %v", waits, want)
+	}
+}
+
+func Test_Supervisor_Resets_Backoff_After_Long_Run(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var waits []time.Duration
+	clock := time.Now()
+	supervisor := NewSupervisor(time.Second, 4*time.Second)
+	supervisor.after = immediately(&waits)
+	supervisor.now = func() time.Time {
+		return clock
+	}
+	calls := 0
+
+	// Act
+	supervisor.Go(ctx, "task", func(ctx context.Context) error {
+		calls++
+		switch calls {
+		case 3:
+			clock = clock.Add(4 * time.Second)
+		case 4:
+			cancel()
+		}
+
+		return taskErr
+	})
+	err := supervisor.Wait(time.Second)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("err: got = %v, want = nil", err)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, time.Second}
+	if !reflect.DeepEqual(waits, want) {
+		t.Errorf("waits: got = %v, want = %v", waits, want)
+	}
+}
+
+func Test_Supervisor_Stops_Waiting_To_Restart_When_Cancelled(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	waiting := make(chan struct{})
+	supervisor := NewSupervisor(time.Second, time.Minute)
+	supervisor.after = func(time.Duration) <-chan time.Time {
+		close(waiting)
+		return nil
+	}
+	calls := 0
+	supervisor.Go(ctx, "task", func(ctx context.Context) error {
+		calls++
+		return taskErr
+	})
+	<-waiting
+
+	// Act
+	cancel()
+	err := supervisor.Wait(time.Second)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("err: got = %v, want = nil", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("calls: got = %d, want = 1", calls)
+	}
+}
+
+func Test_Supervisor_Wait_Times_Out(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	supervisor := NewSupervisor(time.Second, time.Minute)
+	supervisor.Go(ctx, "task", func(context.Context) error {
+		<-release
+		return nil
+	})
+
+	// Act
+	cancel()
+	err := supervisor.Wait(10 * time.Millisecond)
+
+	// Assert
+	if !errors.Is(err, ShutdownTimeoutErr) {
+		t.Errorf("err: got = %v, want = %v", err, ShutdownTimeoutErr)
+	}
+}
@@ -3,7 +3,7 @@ package server
 import (
 	"context"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 	"os/signal"
 	"syscall"
@@ -12,7 +12,8 @@ import (
 
 type (
 	Server struct {
-		handler http.Handler
+		handler    http.Handler
+		onShutdown []func(ctx context.Context) error
 	}
 )
 
@@ -22,6 +23,13 @@ func New(handler http.Handler) *Server {
 	}
 }
 
+// OnShutdown registers fn to run once the server stopped serving requests,
+// sharing the shutdown timeout, for work that outlives the requests that
+// started it.
+func (s *Server) OnShutdown(fn func(ctx context.Context) error) {
+	s.onShutdown = append(s.onShutdown, fn)
+}
+
 func (s *Server) StartWithGracefulShutdown(ctx context.Context, addr string) error {
 	server := http.Server{
 		Addr:    addr,
@@ -36,7 +44,7 @@ func (s *Server) StartWithGracefulShutdown(ctx context.Context, addr string) err
 		}
 	}()
 
-	log.Println("started", addr)
+	slog.InfoContext(ctx, "server started", "addr", addr)
 	notifyCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 
 	for {
@@ -44,7 +52,7 @@ func (s *Server) StartWithGracefulShutdown(ctx context.Context, addr string) err
 		case err := <-errs:
 			return err
 		case <-notifyCtx.Done():
-			log.Println("shutting down", addr)
+			slog.InfoContext(ctx, "server shutting down", "addr", addr)
 			stop()
 
 			withTimeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
@@ -53,8 +61,15 @@ func (s *Server) StartWithGracefulShutdown(ctx context.Context, addr string) err
 				return err
 			}
 
+			for _, fn := range s.onShutdown {
+				if err := fn(withTimeoutCtx); err != nil {
+					cancel()
+					return err
+				}
+			}
+
 			cancel()
-			log.Println("stopped", addr)
+			slog.InfoContext(ctx, "server stopped", "addr", addr)
 			return nil
 		}
 	}
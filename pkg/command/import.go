@@ -0,0 +1,565 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"cqrs-sample/pkg/song"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CSVFormat   = "csv"
+	JSONLFormat = "jsonl"
+
+	maxImportLineSize = 1024 * 1024
+	listSeparator     = "|"
+
+	// importLease is how long a claim holds a job without the run renewing
+	// it. A job whose run crashed is running with an expired lease, so it
+	// can be claimed again once the lease ran out.
+	importLease = 2 * time.Minute
+)
+
+var (
+	UnsupportedImportFormatErr = errors.New("unsupported import format")
+	EmptyImportErr             = errors.New("import has no records")
+	ImportRunningErr           = errors.New("import already running")
+)
+
+type (
+	ImportDatabase interface {
+		SongDatabase
+		GetArtistByName(ctx context.Context, name string) (song.Artist, error)
+		GetAlbumByArtistAndTitle(ctx context.Context, artistID, title string) (song.Album, error)
+		GetSongByPosition(ctx context.Context, albumID string, discNumber, trackNumber int) (song.Song, error)
+		CreateImportJob(ctx context.Context, job *song.ImportJob) error
+		GetImportJobByID(ctx context.Context, id string) (song.ImportJob, error)
+		SaveImportRow(ctx context.Context, jobID string, row song.ImportRow) error
+		UpdateImportJobStatus(ctx context.Context, id string, status song.ImportStatus) error
+		ClaimImportJob(ctx context.Context, id string, now time.Time, lease time.Duration) (bool, error)
+		RenewImportLease(ctx context.Context, id string, until time.Time) error
+	}
+
+	CreateImportCommand struct {
+		Format string
+		Data   io.Reader
+	}
+
+	CreateImport struct {
+		db ImportDatabase
+	}
+
+	RunImport struct {
+		db              ImportDatabase
+		subscribeArtist *SubscribeArtist
+		createAlbum     *CreateAlbum
+		publishSong     *PublishSong
+		now             func() time.Time
+	}
+
+	GetImport struct {
+		db ImportDatabase
+	}
+
+	importRecord struct {
+		Type        string   `json:"type"`
+		Artist      string   `json:"artist"`
+		Gender      string   `json:"gender"`
+		Genres      []string `json:"genres"`
+		Album       string   `json:"album"`
+		ReleaseYear int      `json:"release_year"`
+		Title       string   `json:"title"`
+		TrackNumber int      `json:"track_number"`
+		DiscNumber  int      `json:"disc_number"`
+		DurationMs  int64    `json:"duration_ms"`
+		ISRC        string   `json:"isrc"`
+		Explicit    bool     `json:"explicit"`
+		Composers   []string `json:"composers"`
+	}
+)
+
+func NewCreateImport(db ImportDatabase) *CreateImport {
+	return &CreateImport{
+		db: db,
+	}
+}
+
+func NewRunImport(db ImportDatabase, pub Publisher) *RunImport {
+	return &RunImport{
+		db:              db,
+		subscribeArtist: NewSubscribeArtist(db, pub),
+		createAlbum:     NewCreateAlbum(db, pub),
+		publishSong:     NewPublishSong(db, pub),
+		now:             time.Now,
+	}
+}
+
+func NewGetImport(db ImportDatabase) *GetImport {
+	return &GetImport{
+		db: db,
+	}
+}
+
+func (ci CreateImport) Execute(ctx context.Context, cmd CreateImportCommand) (song.ImportJob, error) {
+	rows, err := ParseImport(cmd.Format, cmd.Data)
+	if err != nil {
+		return song.ImportJob{}, err
+	}
+
+	job := &song.ImportJob{
+		ID:     uuid.NewString(),
+		Format: cmd.Format,
+		Status: song.ImportPending,
+		Rows:   rows,
+	}
+	if err := ci.db.CreateImportJob(ctx, job); err != nil {
+		return song.ImportJob{}, err
+	}
+
+	return *job, nil
+}
+
+// Execute claims the job and imports its pending rows, see Run.
+func (ri RunImport) Execute(ctx context.Context, id string) (song.ImportJob, error) {
+	job, err := ri.Claim(ctx, id)
+	if err != nil || job.Status == song.ImportCompleted {
+		return job, err
+	}
+
+	return ri.Run(ctx, job)
+}
+
+// Claim marks the job running so no other run can pick it up, failing with
+// ImportRunningErr while one holds an unexpired lease on it. A completed job
+// is returned unclaimed.
+func (ri RunImport) Claim(ctx context.Context, id string) (song.ImportJob, error) {
+	job, err := ri.db.GetImportJobByID(ctx, id)
+	if err != nil {
+		return song.ImportJob{}, err
+	}
+
+	if job.Status == song.ImportCompleted {
+		return job, nil
+	}
+
+	ok, err := ri.db.ClaimImportJob(ctx, job.ID, ri.now(), importLease)
+	if err != nil {
+		return job, err
+	}
+
+	if !ok {
+		job, err = ri.db.GetImportJobByID(ctx, id)
+		if err != nil || job.Status == song.ImportCompleted {
+			return job, err
+		}
+
+		return job, ImportRunningErr
+	}
+
+	job.Status = song.ImportRunning
+	return job, nil
+}
+
+// Run imports every pending row of a claimed job, in file order. Rows rejected
+// by the commands are recorded as failed and the import moves on; any other
+// error stops the run and releases the claim leaving the remaining rows
+// pending, so running the job again resumes where it stopped. Cancelling ctx
+// stops the run the same way after the row in progress. The lease taken by
+// Claim is renewed halfway through, so a live run keeps the job.
+func (ri RunImport) Run(ctx context.Context, job song.ImportJob) (song.ImportJob, error) {
+	renewAt := ri.now().Add(importLease / 2)
+	for i := range job.Rows {
+		row := &job.Rows[i]
+		if row.Status != song.RowPending {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return ri.release(ctx, job, err)
+		}
+
+		if now := ri.now(); !now.Before(renewAt) {
+			if err := ri.db.RenewImportLease(ctx, job.ID, now.Add(importLease)); err != nil {
+				return ri.release(ctx, job, err)
+			}
+			renewAt = now.Add(importLease / 2)
+		}
+
+		resourceID, err := ri.importRecord(ctx, row.Record)
+		switch {
+		case err == nil:
+			row.Succeed(resourceID)
+		case errors.Is(err, InvalidCommandErr):
+			row.Fail(err)
+		default:
+			return ri.release(ctx, job, fmt.Errorf("line %d: %w", row.Line, err))
+		}
+
+		if err := ri.db.SaveImportRow(ctx, job.ID, *row); err != nil {
+			return ri.release(ctx, job, err)
+		}
+	}
+
+	if err := ri.db.UpdateImportJobStatus(ctx, job.ID, song.ImportCompleted); err != nil {
+		return ri.release(ctx, job, err)
+	}
+	job.Status = song.ImportCompleted
+
+	return job, nil
+}
+
+// release puts the job back to pending, even once ctx is cancelled, so it
+// can be resumed without waiting for the lease to run out.
+func (ri RunImport) release(ctx context.Context, job song.ImportJob, err error) (song.ImportJob, error) {
+	ctx = context.WithoutCancel(ctx)
+	if releaseErr := ri.db.UpdateImportJobStatus(ctx, job.ID, song.ImportPending); releaseErr != nil {
+		return job, errors.Join(err, releaseErr)
+	}
+	job.Status = song.ImportPending
+
+	return job, err
+}
+
+func (ri RunImport) importRecord(ctx context.Context, r song.ImportRecord) (string, error) {
+	switch r.Type {
+	case song.ArtistRecord:
+		return ri.importArtist(ctx, r)
+	case song.AlbumRecord:
+		return ri.importAlbum(ctx, r)
+	case song.TrackRecord:
+		return ri.importTrack(ctx, r)
+	default:
+		return "", fmt.Errorf("%w: unknown record type %q", InvalidCommandErr, r.Type)
+	}
+}
+
+func (ri RunImport) importArtist(ctx context.Context, r song.ImportRecord) (string, error) {
+	if r.Artist == "" {
+		return "", fmt.Errorf("%w: artist is required", InvalidCommandErr)
+	}
+
+	artist, err := ri.db.GetArtistByName(ctx, r.Artist)
+	if err == nil {
+		return artist.ID, nil
+	}
+
+	if !errors.Is(err, song.NotFoundErr) {
+		return "", err
+	}
+
+	artist, err = ri.subscribeArtist.Execute(ctx, SubscribeArtistCommand{
+		Name:   r.Artist,
+		Gender: song.Gender(r.Gender),
+		Genres: r.Genres,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return artist.ID, nil
+}
+
+func (ri RunImport) importAlbum(ctx context.Context, r song.ImportRecord) (string, error) {
+	if r.Album == "" {
+		return "", fmt.Errorf("%w: album is required", InvalidCommandErr)
+	}
+
+	artist, err := ri.resolveArtist(ctx, r.Artist)
+	if err != nil {
+		return "", err
+	}
+
+	album, err := ri.db.GetAlbumByArtistAndTitle(ctx, artist.ID, r.Album)
+	if err == nil {
+		return album.ID, nil
+	}
+
+	if !errors.Is(err, song.NotFoundErr) {
+		return "", err
+	}
+
+	album, err = ri.createAlbum.Execute(ctx, CreateAlbumCommand{
+		Title:       r.Album,
+		ArtistID:    artist.ID,
+		ReleaseYear: r.ReleaseYear,
+		Genres:      r.Genres,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return album.ID, nil
+}
+
+func (ri RunImport) importTrack(ctx context.Context, r song.ImportRecord) (string, error) {
+	if r.Title == "" {
+		return "", fmt.Errorf("%w: title is required", InvalidCommandErr)
+	}
+
+	album, err := ri.resolveAlbum(ctx, r.Artist, r.Album)
+	if err != nil {
+		return "", err
+	}
+
+	discNumber := r.DiscNumber
+	if discNumber == 0 {
+		discNumber = 1
+	}
+
+	s, err := ri.db.GetSongByPosition(ctx, album.ID, discNumber, r.TrackNumber)
+	if err == nil {
+		return s.ID, nil
+	}
+
+	if !errors.Is(err, song.NotFoundErr) {
+		return "", err
+	}
+
+	s, err = ri.publishSong.Execute(ctx, PublishSongCommand{
+		TrackNumber: r.TrackNumber,
+		DiscNumber:  discNumber,
+		Title:       r.Title,
+		AlbumID:     album.ID,
+		Duration:    r.Duration,
+		ISRC:        r.ISRC,
+		Explicit:    r.Explicit,
+		Composers:   r.Composers,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return s.ID, nil
+}
+
+func (ri RunImport) resolveArtist(ctx context.Context, name string) (song.Artist, error) {
+	artist, err := ri.db.GetArtistByName(ctx, name)
+	if errors.Is(err, song.NotFoundErr) {
+		return song.Artist{}, fmt.Errorf("%w: artist %q not found", InvalidCommandErr, name)
+	}
+
+	return artist, err
+}
+
+func (ri RunImport) resolveAlbum(ctx context.Context, artistName, title string) (song.Album, error) {
+	artist, err := ri.resolveArtist(ctx, artistName)
+	if err != nil {
+		return song.Album{}, err
+	}
+
+	album, err := ri.db.GetAlbumByArtistAndTitle(ctx, artist.ID, title)
+	if errors.Is(err, song.NotFoundErr) {
+		return song.Album{}, fmt.Errorf("%w: album %q by %q not found", InvalidCommandErr, title, artistName)
+	}
+
+	return album, err
+}
+
+func (gi GetImport) Execute(ctx context.Context, id string) (song.ImportJob, error) {
+	return gi.db.GetImportJobByID(ctx, id)
+}
+
+// ParseImport reads the records of a CSV or JSON Lines file. Rows that can't
+// be decoded are returned already failed so they show up in the job report;
+// only an unreadable file as a whole is an error.
+func ParseImport(format string, r io.Reader) ([]song.ImportRow, error) {
+	var (
+		rows []song.ImportRow
+		err  error
+	)
+	switch format {
+	case CSVFormat:
+		rows, err = parseCSV(r)
+	case JSONLFormat:
+		rows, err = parseJSONL(r)
+	default:
+		return nil, fmt.Errorf("%w: %w %q", InvalidCommandErr, UnsupportedImportFormatErr, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", InvalidCommandErr, err)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: %w", InvalidCommandErr, EmptyImportErr)
+	}
+
+	return rows, nil
+}
+
+func parseJSONL(r io.Reader) ([]song.ImportRow, error) {
+	var rows []song.ImportRow
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxImportLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var record importRecord
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			rows = append(rows, failedRow(line, err))
+			continue
+		}
+
+		rows = append(rows, song.ImportRow{
+			Line:   line,
+			Record: record.toDomain(),
+			Status: song.RowPending,
+		})
+	}
+
+	return rows, scanner.Err()
+}
+
+func parseCSV(r io.Reader) ([]song.ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if _, ok := columns["type"]; !ok {
+		return nil, errors.New(`missing "type" column`)
+	}
+
+	var rows []song.ImportRow
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		record, err := newImportRecordFromCSV(columns, fields)
+		if err != nil {
+			rows = append(rows, failedRow(line, err))
+			continue
+		}
+
+		rows = append(rows, song.ImportRow{
+			Line:   line,
+			Record: record.toDomain(),
+			Status: song.RowPending,
+		})
+	}
+
+	return rows, nil
+}
+
+func newImportRecordFromCSV(columns map[string]int, fields []string) (importRecord, error) {
+	get := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(fields) {
+			return ""
+		}
+
+		return strings.TrimSpace(fields[i])
+	}
+
+	record := importRecord{
+		Type:      get("type"),
+		Artist:    get("artist"),
+		Gender:    get("gender"),
+		Genres:    splitList(get("genres")),
+		Album:     get("album"),
+		Title:     get("title"),
+		ISRC:      get("isrc"),
+		Composers: splitList(get("composers")),
+	}
+
+	var err error
+	if record.ReleaseYear, err = atoi(get("release_year")); err != nil {
+		return importRecord{}, fmt.Errorf("release_year: %w", err)
+	}
+
+	if record.TrackNumber, err = atoi(get("track_number")); err != nil {
+		return importRecord{}, fmt.Errorf("track_number: %w", err)
+	}
+
+	if record.DiscNumber, err = atoi(get("disc_number")); err != nil {
+		return importRecord{}, fmt.Errorf("disc_number: %w", err)
+	}
+
+	if v := get("duration_ms"); v != "" {
+		if record.DurationMs, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return importRecord{}, fmt.Errorf("duration_ms: %w", err)
+		}
+	}
+
+	if v := get("explicit"); v != "" {
+		if record.Explicit, err = strconv.ParseBool(v); err != nil {
+			return importRecord{}, fmt.Errorf("explicit: %w", err)
+		}
+	}
+
+	return record, nil
+}
+
+func (r importRecord) toDomain() song.ImportRecord {
+	return song.ImportRecord{
+		Type:        song.RecordType(strings.ToLower(strings.TrimSpace(r.Type))),
+		Artist:      strings.TrimSpace(r.Artist),
+		Gender:      r.Gender,
+		Genres:      r.Genres,
+		Album:       strings.TrimSpace(r.Album),
+		ReleaseYear: r.ReleaseYear,
+		Title:       strings.TrimSpace(r.Title),
+		TrackNumber: r.TrackNumber,
+		DiscNumber:  r.DiscNumber,
+		Duration:    time.Duration(r.DurationMs) * time.Millisecond,
+		ISRC:        r.ISRC,
+		Explicit:    r.Explicit,
+		Composers:   r.Composers,
+	}
+}
+
+func failedRow(line int, err error) song.ImportRow {
+	return song.ImportRow{
+		Line:   line,
+		Status: song.RowFailed,
+		Error:  fmt.Sprintf("%s: %s", InvalidCommandErr, err),
+	}
+}
+
+func splitList(v string) []string {
+	if v == "" {
+		return nil
+	}
+
+	var output []string
+	for _, item := range strings.Split(v, listSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			output = append(output, item)
+		}
+	}
+
+	return output
+}
+
+func atoi(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(v)
+}
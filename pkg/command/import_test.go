@@ -0,0 +1,244 @@
+package command
+
+import (
+	"context"
+	"cqrs-sample/pkg/event"
+	"cqrs-sample/pkg/song"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const catalogCSV = `type,artist,gender,genres,album,release_year,title,track_number,disc_number,duration_ms,isrc,composers
+artist,Some Artist,Some Gender,rock|indie,,,,,,,,
+album,Some Artist,,rock,Some Album,2024,,,,,,
+track,Some Artist,,,Some Album,,First Song,1,,180000,US-ABC-24-00001,Someone|Someone Else
+track,Some Artist,,,Some Album,,Second Song,two,,,,
+track,Unknown Artist,,,Other Album,,Lost Song,1,,,,
+track,Some Artist,,,Some Album,,Third Song,3,,200000,,
+`
+
+type failingPublisher struct {
+	recordingPublisher
+	failures int
+}
+
+func (f *failingPublisher) Publish(ctx context.Context, m event.Message, e event.Event) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("broker unavailable")
+	}
+
+	return f.recordingPublisher.Publish(ctx, m, e)
+}
+
+func Test_Import_Catalog_From_CSV(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	publisher := &recordingPublisher{}
+	ctx := context.Background()
+
+	job, err := NewCreateImport(db).Execute(ctx, CreateImportCommand{
+		Format: CSVFormat,
+		Data:   strings.NewReader(catalogCSV),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	job, err = NewRunImport(db, publisher).Execute(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	wantStatuses := []song.RowStatus{
+		song.RowSucceeded,
+		song.RowSucceeded,
+		song.RowSucceeded,
+		song.RowFailed,
+		song.RowFailed,
+		song.RowSucceeded,
+	}
+	if len(job.Rows) != len(wantStatuses) {
+		t.Fatalf("rows: got = %d, want = %d", len(job.Rows), len(wantStatuses))
+	}
+
+	for i, want := range wantStatuses {
+		if got := job.Rows[i].Status; got != want {
+			t.Errorf("line %d: got = %q, want = %q (%s)", job.Rows[i].Line, got, want, job.Rows[i].Error)
+		}
+	}
+
+	if job.Status != song.ImportCompleted {
+		t.Errorf("job status: got = %q, want = %q", job.Status, song.ImportCompleted)
+	}
+
+	first, err := db.GetSongByID(ctx, job.Rows[2].ResourceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Album.ID != job.Rows[1].ResourceID || first.ISRC != "USABC2400001" || first.DiscNumber != 1 {
+		t.Errorf("first song = %+v", first)
+	}
+
+	if n := publisher.count(event.SongPublishedEvent); n != 2 {
+		t.Errorf("song published events: got = %d, want = 2", n)
+	}
+}
+
+func Test_Import_Resumes_After_Interruption(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	ctx := context.Background()
+	jsonl := strings.Join([]string{
+		`{"type":"artist","artist":"Some Artist"}`,
+		`{"type":"album","artist":"Some Artist","album":"Some Album"}`,
+		``,
+		`{"type":"track","artist":"Some Artist","album":"Some Album","title":"Some Song","track_number":1}`,
+		`not json`,
+	}, "\n")
+
+	job, err := NewCreateImport(db).Execute(ctx, CreateImportCommand{
+		Format: JSONLFormat,
+		Data:   strings.NewReader(jsonl),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	interrupted, interruptErr := NewRunImport(db, &failingPublisher{failures: 1}).Execute(ctx, job.ID)
+	resumed, err := NewRunImport(db, &recordingPublisher{}).Execute(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	if interruptErr == nil || interrupted.Status != song.ImportPending {
+		t.Errorf("interrupted: status = %q, err = %v", interrupted.Status, interruptErr)
+	}
+
+	if n := interrupted.Count(song.RowPending); n != 3 {
+		t.Errorf("pending after interruption: got = %d, want = 3", n)
+	}
+
+	if resumed.Status != song.ImportCompleted {
+		t.Errorf("resumed status: got = %q, want = %q", resumed.Status, song.ImportCompleted)
+	}
+
+	if n := resumed.Count(song.RowSucceeded); n != 3 {
+		t.Errorf("succeeded: got = %d, want = 3", n)
+	}
+
+	if last := resumed.Rows[len(resumed.Rows)-1]; last.Line != 5 || last.Status != song.RowFailed {
+		t.Errorf("malformed line: got = %+v", last)
+	}
+
+	artist, err := db.GetArtistByName(ctx, "Some Artist")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if artist.ID != resumed.Rows[0].ResourceID {
+		t.Errorf("artist: got = %s, want = %s", resumed.Rows[0].ResourceID, artist.ID)
+	}
+}
+
+func Test_Import_Is_Claimed_By_One_Run(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	ctx := context.Background()
+	job, err := NewCreateImport(db).Execute(ctx, CreateImportCommand{
+		Format: JSONLFormat,
+		Data:   strings.NewReader(`{"type":"artist","artist":"Some Artist"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := NewRunImport(db, &recordingPublisher{})
+
+	// Act
+	claimed, err := runner.Claim(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, claimErr := runner.Claim(ctx, job.ID)
+	completed, err := runner.Run(ctx, claimed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reclaimed, reclaimErr := runner.Claim(ctx, job.ID)
+
+	// Assert
+	if !errors.Is(claimErr, ImportRunningErr) {
+		t.Errorf("second claim: got = %v, want = %v", claimErr, ImportRunningErr)
+	}
+
+	if completed.Status != song.ImportCompleted {
+		t.Errorf("run status: got = %q, want = %q", completed.Status, song.ImportCompleted)
+	}
+
+	if reclaimErr != nil || reclaimed.Status != song.ImportCompleted {
+		t.Errorf("claim after completion: status = %q, err = %v", reclaimed.Status, reclaimErr)
+	}
+}
+
+func Test_Import_With_Expired_Lease_Is_Reclaimed(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	ctx := context.Background()
+	job, err := NewCreateImport(db).Execute(ctx, CreateImportCommand{
+		Format: JSONLFormat,
+		Data:   strings.NewReader(`{"type":"artist","artist":"Some Artist"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crashed := NewRunImport(db, &recordingPublisher{})
+	if _, err := crashed.Claim(ctx, job.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := NewRunImport(db, &recordingPublisher{})
+	now := time.Now()
+
+	// Act
+	runner.now = func() time.Time { return now.Add(importLease / 2) }
+	_, leasedErr := runner.Claim(ctx, job.ID)
+
+	runner.now = func() time.Time { return now.Add(importLease + time.Second) }
+	reclaimed, reclaimErr := runner.Claim(ctx, job.ID)
+
+	// Assert
+	if !errors.Is(leasedErr, ImportRunningErr) {
+		t.Errorf("claim within lease: got = %v, want = %v", leasedErr, ImportRunningErr)
+	}
+
+	if reclaimErr != nil || reclaimed.Status != song.ImportRunning {
+		t.Errorf("claim after lease: status = %q, err = %v", reclaimed.Status, reclaimErr)
+	}
+}
@@ -5,7 +5,18 @@ import (
 	"cqrs-sample/pkg/event"
 	"cqrs-sample/pkg/message"
 	"cqrs-sample/pkg/song"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"time"
+)
+
+// AnyVersion is the expected version of a command that must apply to
+// whatever version the aggregate is at, as sent with If-Match: *.
+const AnyVersion = -1
+
+var (
+	InvalidCommandErr = errors.New("invalid command")
 )
 
 type (
@@ -24,6 +35,12 @@ type (
 		AlbumDatabase
 		ArtistDatabase
 		CreateSong(ctx context.Context, s *song.Song) error
+		ExistsSongWithISRC(ctx context.Context, isrc string) (bool, error)
+	}
+
+	MergeDatabase interface {
+		ArtistDatabase
+		MergeArtists(ctx context.Context, sourceID string, target song.Artist, mergedAt time.Time) error
 	}
 
 	Publisher interface {
@@ -33,18 +50,42 @@ type (
 	SubscribeArtistCommand struct {
 		Name   string
 		Gender song.Gender
+		Genres []string
+	}
+
+	MergeArtistsCommand struct {
+		SourceID string
+		TargetID string
+		Version  int
 	}
 
-	PublishAlbumCommand struct {
+	CreateAlbumCommand struct {
 		Title       string
 		ArtistID    string
 		ReleaseYear int
+		Genres      []string
 	}
 
 	PublishSongCommand struct {
 		TrackNumber int
+		DiscNumber  int
 		Title       string
 		AlbumID     string
+		Duration    time.Duration
+		ISRC        string
+		Explicit    bool
+		Composers   []string
+		Featuring   []FeaturedArtist
+	}
+
+	FeaturedArtist struct {
+		ArtistID string
+		Role     song.Role
+	}
+
+	PlaySongCommand struct {
+		SongID     string
+		ListenerID string
 	}
 
 	SubscribeArtist struct {
@@ -52,7 +93,12 @@ type (
 		pub Publisher
 	}
 
-	PublishAlbum struct {
+	MergeArtists struct {
+		db  MergeDatabase
+		pub Publisher
+	}
+
+	CreateAlbum struct {
 		db  AlbumDatabase
 		pub Publisher
 	}
@@ -63,6 +109,7 @@ type (
 	}
 
 	PlaySong struct {
+		db  ListenerDatabase
 		pub Publisher
 	}
 )
@@ -74,8 +121,15 @@ func NewSubscribeArtist(db ArtistDatabase, pub Publisher) *SubscribeArtist {
 	}
 }
 
-func NewPublishAlbum(db AlbumDatabase, pub Publisher) *PublishAlbum {
-	return &PublishAlbum{
+func NewMergeArtists(db MergeDatabase, pub Publisher) *MergeArtists {
+	return &MergeArtists{
+		db:  db,
+		pub: pub,
+	}
+}
+
+func NewCreateAlbum(db AlbumDatabase, pub Publisher) *CreateAlbum {
+	return &CreateAlbum{
 		db:  db,
 		pub: pub,
 	}
@@ -88,23 +142,26 @@ func NewPublishSong(db SongDatabase, pub Publisher) *PublishSong {
 	}
 }
 
-func NewPlaySong(pub Publisher) *PlaySong {
+func NewPlaySong(db ListenerDatabase, pub Publisher) *PlaySong {
 	return &PlaySong{
+		db:  db,
 		pub: pub,
 	}
 }
 
 func (ca SubscribeArtist) Execute(ctx context.Context, cmd SubscribeArtistCommand) (song.Artist, error) {
 	artist := &song.Artist{
-		ID:     uuid.NewString(),
-		Name:   cmd.Name,
-		Gender: cmd.Gender,
+		ID:      uuid.NewString(),
+		Name:    cmd.Name,
+		Gender:  cmd.Gender,
+		Genres:  song.NewGenres(cmd.Genres),
+		Version: 1,
 	}
 	if err := ca.db.CreateArtist(ctx, artist); err != nil {
 		return song.Artist{}, err
 	}
 
-	m := event.NewMessage(message.NewArtistFromDomain(*artist))
+	m := event.NewAggregateMessage(artist.ID, message.NewArtistFromDomain(*artist))
 	if err := ca.pub.Publish(ctx, m, event.ArtistSubscribedEvent); err != nil {
 		return song.Artist{}, err
 	}
@@ -112,7 +169,48 @@ func (ca SubscribeArtist) Execute(ctx context.Context, cmd SubscribeArtistComman
 	return *artist, nil
 }
 
-func (ca PublishAlbum) Execute(ctx context.Context, cmd PublishAlbumCommand) (song.Album, error) {
+func (ma MergeArtists) Execute(ctx context.Context, cmd MergeArtistsCommand) (song.ArtistMerge, error) {
+	if cmd.SourceID == cmd.TargetID {
+		return song.ArtistMerge{}, fmt.Errorf("%w: %w", InvalidCommandErr, song.SelfMergeErr)
+	}
+
+	if _, err := ma.db.GetArtistByID(ctx, cmd.SourceID); err != nil {
+		return song.ArtistMerge{}, err
+	}
+
+	target, err := ma.db.GetArtistByID(ctx, cmd.TargetID)
+	if err != nil {
+		return song.ArtistMerge{}, err
+	}
+
+	if err := checkVersion(target.Version, cmd.Version); err != nil {
+		return song.ArtistMerge{}, err
+	}
+
+	mergedAt := time.Now().UTC()
+	if err := ma.db.MergeArtists(ctx, cmd.SourceID, target, mergedAt); err != nil {
+		return song.ArtistMerge{}, err
+	}
+
+	target, err = ma.db.GetArtistByID(ctx, cmd.TargetID)
+	if err != nil {
+		return song.ArtistMerge{}, err
+	}
+
+	merge := song.ArtistMerge{
+		SourceID: cmd.SourceID,
+		Target:   target,
+		MergedAt: mergedAt,
+	}
+	m := event.NewAggregateMessage(merge.Target.ID, message.NewArtistMergeFromDomain(merge))
+	if err := ma.pub.Publish(ctx, m, event.ArtistMergedEvent); err != nil {
+		return song.ArtistMerge{}, err
+	}
+
+	return merge, nil
+}
+
+func (ca CreateAlbum) Execute(ctx context.Context, cmd CreateAlbumCommand) (song.Album, error) {
 	artist, err := ca.db.GetArtistByID(ctx, cmd.ArtistID)
 	if err != nil {
 		return song.Album{}, err
@@ -123,13 +221,16 @@ func (ca PublishAlbum) Execute(ctx context.Context, cmd PublishAlbumCommand) (so
 		Title:       cmd.Title,
 		Artist:      artist,
 		ReleaseYear: cmd.ReleaseYear,
+		Status:      song.DraftStatus,
+		Genres:      song.NewGenres(cmd.Genres),
+		Version:     1,
 	}
 	if err := ca.db.CreateAlbum(ctx, album); err != nil {
 		return song.Album{}, err
 	}
 
-	m := event.NewMessage(message.NewAlbumFromDomain(*album))
-	if err := ca.pub.Publish(ctx, m, event.AlbumPublishedEvent); err != nil {
+	m := event.NewAggregateMessage(album.ID, message.NewAlbumFromDomain(*album))
+	if err := ca.pub.Publish(ctx, m, event.AlbumCreatedEvent); err != nil {
 		return song.Album{}, err
 	}
 
@@ -137,30 +238,55 @@ func (ca PublishAlbum) Execute(ctx context.Context, cmd PublishAlbumCommand) (so
 }
 
 func (cs PublishSong) Execute(ctx context.Context, cmd PublishSongCommand) (song.Song, error) {
+	if cmd.Duration < 0 {
+		return song.Song{}, fmt.Errorf("%w: duration must not be negative", InvalidCommandErr)
+	}
+
+	isrc, err := cs.checkISRC(ctx, cmd.ISRC)
+	if err != nil {
+		return song.Song{}, err
+	}
+
 	album, err := cs.db.GetAlbumByID(ctx, cmd.AlbumID)
 	if err != nil {
 		return song.Song{}, err
 	}
 
+	if !album.AcceptsSongs() {
+		return song.Song{}, fmt.Errorf("%w: %w", InvalidCommandErr, song.AlbumNotDraftErr)
+	}
+
 	artist, err := cs.db.GetArtistByID(ctx, album.Artist.ID)
 	if err != nil {
 		return song.Song{}, err
 	}
 
+	featuring, err := cs.resolveFeaturing(ctx, artist, cmd.Featuring)
+	if err != nil {
+		return song.Song{}, err
+	}
+
 	album.Artist = artist
 	s := &song.Song{
 		ID:          uuid.NewString(),
 		TrackNumber: cmd.TrackNumber,
+		DiscNumber:  cmd.DiscNumber,
 		Title:       cmd.Title,
+		Duration:    cmd.Duration,
+		ISRC:        isrc,
+		Explicit:    cmd.Explicit,
+		Composers:   cmd.Composers,
 		Album:       album,
 		Artist:      artist,
+		Featuring:   featuring,
+		Version:     1,
 	}
 
 	if err := cs.db.CreateSong(ctx, s); err != nil {
 		return song.Song{}, err
 	}
 
-	m := event.NewMessage(message.NewSongFromDomain(*s))
+	m := event.NewAggregateMessage(s.ID, message.NewSongFromDomain(*s))
 	if err := cs.pub.Publish(ctx, m, event.SongPublishedEvent); err != nil {
 		return song.Song{}, err
 	}
@@ -168,9 +294,77 @@ func (cs PublishSong) Execute(ctx context.Context, cmd PublishSongCommand) (song
 	return *s, nil
 }
 
-func (ps PlaySong) Execute(ctx context.Context, songID string) error {
-	m := event.NewMessage(message.PlaySong{
-		SongID: songID,
+func (cs PublishSong) checkISRC(ctx context.Context, code string) (string, error) {
+	if code == "" {
+		return "", nil
+	}
+
+	isrc, err := song.ParseISRC(code)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", InvalidCommandErr, err)
+	}
+
+	exists, err := cs.db.ExistsSongWithISRC(ctx, isrc)
+	if err != nil {
+		return "", err
+	}
+
+	if exists {
+		return "", fmt.Errorf("%w: %w %s", InvalidCommandErr, song.DuplicateISRCErr, isrc)
+	}
+
+	return isrc, nil
+}
+
+func (cs PublishSong) resolveFeaturing(ctx context.Context, main song.Artist, featured []FeaturedArtist) ([]song.Featuring, error) {
+	var output []song.Featuring
+	seen := make(map[string]bool, len(featured))
+	for _, f := range featured {
+		if !f.Role.IsValid() {
+			return nil, fmt.Errorf("%w: invalid role %q", InvalidCommandErr, f.Role)
+		}
+
+		if f.ArtistID == main.ID || seen[f.ArtistID] {
+			return nil, fmt.Errorf("%w: artist %s credited more than once", InvalidCommandErr, f.ArtistID)
+		}
+
+		artist, err := cs.db.GetArtistByID(ctx, f.ArtistID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: featured artist %s: %w", InvalidCommandErr, f.ArtistID, err)
+		}
+
+		seen[f.ArtistID] = true
+		output = append(output, song.Featuring{
+			Artist: artist,
+			Role:   f.Role,
+		})
+	}
+
+	return output, nil
+}
+
+func (ps PlaySong) Execute(ctx context.Context, cmd PlaySongCommand) error {
+	if cmd.ListenerID != "" {
+		if _, err := ps.db.GetListenerByID(ctx, cmd.ListenerID); err != nil {
+			return err
+		}
+	}
+
+	m := event.NewAggregateMessage(cmd.SongID, message.PlaySong{
+		ID:         uuid.NewString(),
+		SongID:     cmd.SongID,
+		ListenerID: cmd.ListenerID,
+		PlayedAt:   time.Now().UTC(),
 	})
 	return ps.pub.Publish(ctx, m, event.SongPlayedEvent)
 }
+
+// checkVersion reports a conflict when the aggregate is no longer at the
+// version the command expects.
+func checkVersion(current, expected int) error {
+	if expected != AnyVersion && current != expected {
+		return song.VersionConflictErr
+	}
+
+	return nil
+}
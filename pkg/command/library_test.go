@@ -5,17 +5,19 @@ import (
 	"cqrs-sample/internal/database"
 	"cqrs-sample/pkg/event"
 	"cqrs-sample/pkg/song"
+	"errors"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"log"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"testing"
 )
 
 func setupDatabase(t *testing.T) *database.Gorm {
 	testDBPath := filepath.Join(t.TempDir(), "database.sqlite")
-	db, err := gorm.Open(sqlite.Open(testDBPath), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(testDBPath), &gorm.Config{TranslateError: true})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -33,7 +35,7 @@ func Test_Create_Artist_Album_And_Song(t *testing.T) {
 	publisher := &fakePublisher{}
 	ctx := context.Background()
 	artistSubscriber := NewSubscribeArtist(db, publisher)
-	albumPublisher := NewPublishAlbum(db, publisher)
+	albumCreator := NewCreateAlbum(db, publisher)
 	songPublisher := NewPublishSong(db, publisher)
 
 	// Act
@@ -45,7 +47,7 @@ func Test_Create_Artist_Album_And_Song(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	album, err := albumPublisher.Execute(ctx, PublishAlbumCommand{
+	album, err := albumCreator.Execute(ctx, CreateAlbumCommand{
 		Title:       "Some Album",
 		ArtistID:    artist.ID,
 		ReleaseYear: 2024,
@@ -72,25 +74,168 @@ func Test_Create_Artist_Album_And_Song(t *testing.T) {
 			ID:    album.ID,
 			Title: "Some Album",
 			Artist: song.Artist{
-				ID:     artist.ID,
-				Name:   "Some Artist",
-				Gender: "Some Gender",
-				Albums: make([]song.Album, 0),
+				ID:      artist.ID,
+				Name:    "Some Artist",
+				Gender:  "Some Gender",
+				Albums:  make([]song.Album, 0),
+				Version: 1,
 			},
 			ReleaseYear: 2024,
+			Status:      song.DraftStatus,
+			Version:     1,
 		},
 		Artist: song.Artist{
-			ID:     artist.ID,
-			Name:   "Some Artist",
-			Gender: "Some Gender",
-			Albums: make([]song.Album, 0),
+			ID:      artist.ID,
+			Name:    "Some Artist",
+			Gender:  "Some Gender",
+			Albums:  make([]song.Album, 0),
+			Version: 1,
 		},
+		Version: 1,
 	}
 	if !reflect.DeepEqual(s, wantSong) {
 		t.Errorf("\n\tgot = %+v\n\twant= %+v", s, wantSong)
 	}
 }
 
+func Test_Publish_Song_With_Featured_Artists(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	publisher := &fakePublisher{}
+	ctx := context.Background()
+	artistSubscriber := NewSubscribeArtist(db, publisher)
+	songPublisher := NewPublishSong(db, publisher)
+
+	artist, err := artistSubscriber.Execute(ctx, SubscribeArtistCommand{Name: "Some Artist"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	guest, err := artistSubscriber.Execute(ctx, SubscribeArtistCommand{Name: "Some Guest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	album, err := NewCreateAlbum(db, publisher).Execute(ctx, CreateAlbumCommand{
+		Title:    "Some Album",
+		ArtistID: artist.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	s, err := songPublisher.Execute(ctx, PublishSongCommand{
+		TrackNumber: 1,
+		Title:       "Some Song",
+		AlbumID:     album.ID,
+		Featuring:   []FeaturedArtist{{ArtistID: guest.ID, Role: song.FeaturedRole}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, invalidRoleErr := songPublisher.Execute(ctx, PublishSongCommand{
+		TrackNumber: 2,
+		Title:       "Other Song",
+		AlbumID:     album.ID,
+		Featuring:   []FeaturedArtist{{ArtistID: guest.ID, Role: "drummer"}},
+	})
+
+	_, mainArtistErr := songPublisher.Execute(ctx, PublishSongCommand{
+		TrackNumber: 3,
+		Title:       "Another Song",
+		AlbumID:     album.ID,
+		Featuring:   []FeaturedArtist{{ArtistID: artist.ID, Role: song.GuestRole}},
+	})
+
+	// Assert
+	stored, err := db.GetSongByID(ctx, s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantFeaturing := []song.Featuring{{Artist: song.Artist{ID: guest.ID}, Role: song.FeaturedRole}}
+	if !reflect.DeepEqual(stored.Featuring, wantFeaturing) {
+		t.Errorf("\n\tgot = %+v\n\twant= %+v", stored.Featuring, wantFeaturing)
+	}
+
+	if !errors.Is(invalidRoleErr, InvalidCommandErr) {
+		t.Errorf("invalid role: got = %v, want = %v", invalidRoleErr, InvalidCommandErr)
+	}
+
+	if !errors.Is(mainArtistErr, InvalidCommandErr) {
+		t.Errorf("main artist featured: got = %v, want = %v", mainArtistErr, InvalidCommandErr)
+	}
+}
+
+func Test_Publish_Song_Enforces_Unique_ISRC(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	publisher := &fakePublisher{}
+	ctx := context.Background()
+	songPublisher := NewPublishSong(db, publisher)
+
+	artist, err := NewSubscribeArtist(db, publisher).Execute(ctx, SubscribeArtistCommand{Name: "Some Artist"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	album, err := NewCreateAlbum(db, publisher).Execute(ctx, CreateAlbumCommand{
+		Title:    "Some Album",
+		ArtistID: artist.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	s, err := songPublisher.Execute(ctx, PublishSongCommand{
+		TrackNumber: 1,
+		Title:       "Some Song",
+		AlbumID:     album.ID,
+		ISRC:        "us-abc-24-00001",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, duplicateErr := songPublisher.Execute(ctx, PublishSongCommand{
+		TrackNumber: 2,
+		Title:       "Other Song",
+		AlbumID:     album.ID,
+		ISRC:        "USABC2400001",
+	})
+
+	_, malformedErr := songPublisher.Execute(ctx, PublishSongCommand{
+		TrackNumber: 3,
+		Title:       "Another Song",
+		AlbumID:     album.ID,
+		ISRC:        "not-an-isrc",
+	})
+
+	// Assert
+	if s.ISRC != "USABC2400001" {
+		t.Errorf("isrc: got = %s, want = USABC2400001", s.ISRC)
+	}
+
+	if !errors.Is(duplicateErr, song.DuplicateISRCErr) {
+		t.Errorf("duplicate isrc: got = %v, want = %v", duplicateErr, song.DuplicateISRCErr)
+	}
+
+	if !errors.Is(malformedErr, song.InvalidISRCErr) {
+		t.Errorf("malformed isrc: got = %v, want = %v", malformedErr, song.InvalidISRCErr)
+	}
+}
+
 type (
 	fakePublisher struct{}
 )
@@ -98,3 +243,164 @@ type (
 func (f fakePublisher) Publish(_ context.Context, _ event.Message, _ event.Event) error {
 	return nil
 }
+
+func Test_Merge_Artists(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	publisher := &recordingPublisher{}
+	ctx := context.Background()
+	artistSubscriber := NewSubscribeArtist(db, publisher)
+	albumCreator := NewCreateAlbum(db, publisher)
+	songPublisher := NewPublishSong(db, publisher)
+
+	source, err := artistSubscriber.Execute(ctx, SubscribeArtistCommand{Name: "Beyonce", Genres: []string{"pop"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target, err := artistSubscriber.Execute(ctx, SubscribeArtistCommand{Name: "Beyoncé", Genres: []string{"rnb"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sourceAlbum, err := albumCreator.Execute(ctx, CreateAlbumCommand{Title: "Some Album", ArtistID: source.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sourceSong, err := songPublisher.Execute(ctx, PublishSongCommand{
+		TrackNumber: 1,
+		Title:       "Some Song",
+		AlbumID:     sourceAlbum.ID,
+		Featuring:   []FeaturedArtist{{ArtistID: target.ID, Role: song.FeaturedRole}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, staleErr := NewMergeArtists(db, publisher).Execute(ctx, MergeArtistsCommand{
+		SourceID: source.ID,
+		TargetID: target.ID,
+		Version:  target.Version + 1,
+	})
+
+	merge, err := NewMergeArtists(db, publisher).Execute(ctx, MergeArtistsCommand{
+		SourceID: source.ID,
+		TargetID: target.ID,
+		Version:  target.Version,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, selfMergeErr := NewMergeArtists(db, publisher).Execute(ctx, MergeArtistsCommand{
+		SourceID: target.ID,
+		TargetID: target.ID,
+		Version:  AnyVersion,
+	})
+
+	// Assert
+	if !errors.Is(staleErr, song.VersionConflictErr) {
+		t.Errorf("stale merge: got = %v, want = %v", staleErr, song.VersionConflictErr)
+	}
+
+	if _, err := db.GetArtistByID(ctx, source.ID); !errors.Is(err, song.NotFoundErr) {
+		t.Errorf("source artist: got = %v, want = %v", err, song.NotFoundErr)
+	}
+
+	wantGenres := []song.Genre{"pop", "rnb"}
+	if !reflect.DeepEqual(merge.Target.Genres, wantGenres) {
+		t.Errorf("genres: got = %v, want = %v", merge.Target.Genres, wantGenres)
+	}
+
+	if merge.Target.Version != 2 {
+		t.Errorf("target version: got = %d, want = 2", merge.Target.Version)
+	}
+
+	album, err := db.GetAlbumByID(ctx, sourceAlbum.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if album.Artist.ID != target.ID {
+		t.Errorf("album artist: got = %s, want = %s", album.Artist.ID, target.ID)
+	}
+
+	s, err := db.GetSongByID(ctx, sourceSong.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Artist.ID != target.ID || len(s.Featuring) != 0 {
+		t.Errorf("song: artist = %s, featuring = %+v", s.Artist.ID, s.Featuring)
+	}
+
+	if !errors.Is(selfMergeErr, InvalidCommandErr) {
+		t.Errorf("self merge: got = %v, want = %v", selfMergeErr, InvalidCommandErr)
+	}
+
+	if n := publisher.count(event.ArtistMergedEvent); n != 1 {
+		t.Errorf("artist merged events: got = %d, want = 1", n)
+	}
+}
+
+func Test_Genres_Are_Shared_By_Artists_And_Albums(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	publisher := &fakePublisher{}
+	ctx := context.Background()
+
+	// Act
+	artist, err := NewSubscribeArtist(db, publisher).Execute(ctx, SubscribeArtistCommand{
+		Name:   "Some Artist",
+		Gender: "Some Gender",
+		Genres: []string{"Rock", " pop", "rock"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	album, err := NewCreateAlbum(db, publisher).Execute(ctx, CreateAlbumCommand{
+		Title:       "Some Album",
+		ArtistID:    artist.ID,
+		ReleaseYear: 2024,
+		Genres:      []string{"ROCK", "Jazz"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	savedArtist, err := db.GetArtistByID(ctx, artist.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	savedAlbum, err := db.GetAlbumByID(ctx, album.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	if got, want := sortedGenres(savedArtist.Genres), []string{"pop", "rock"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("artist genres: got = %v, want = %v", got, want)
+	}
+
+	if got, want := sortedGenres(savedAlbum.Genres), []string{"jazz", "rock"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("album genres: got = %v, want = %v", got, want)
+	}
+}
+
+func sortedGenres(genres []song.Genre) []string {
+	names := song.GenreNames(genres)
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,213 @@
+package command
+
+import (
+	"context"
+	"cqrs-sample/pkg/event"
+	"cqrs-sample/pkg/message"
+	"cqrs-sample/pkg/song"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+type (
+	ListenerDatabase interface {
+		CreateListener(ctx context.Context, listener *song.Listener) error
+		GetListenerByID(ctx context.Context, id string) (song.Listener, error)
+	}
+
+	FollowDatabase interface {
+		ListenerDatabase
+		ArtistDatabase
+		CreateFollow(ctx context.Context, follow song.Follow) error
+	}
+
+	LikeDatabase interface {
+		ListenerDatabase
+		GetSongByID(ctx context.Context, id string) (song.Song, error)
+		CreateLike(ctx context.Context, like song.Like) (bool, error)
+	}
+
+	RatingDatabase interface {
+		ListenerDatabase
+		GetAlbumByID(ctx context.Context, id string) (song.Album, error)
+		SaveAlbumRating(ctx context.Context, rating song.AlbumRating) error
+	}
+
+	RegisterListenerCommand struct {
+		Name  string
+		Email string
+	}
+
+	FollowArtistCommand struct {
+		ListenerID string
+		ArtistID   string
+	}
+
+	LikeSongCommand struct {
+		ListenerID string
+		SongID     string
+	}
+
+	RateAlbumCommand struct {
+		ListenerID string
+		AlbumID    string
+		Rating     int
+	}
+
+	RegisterListener struct {
+		db  ListenerDatabase
+		pub Publisher
+	}
+
+	FollowArtist struct {
+		db  FollowDatabase
+		pub Publisher
+	}
+
+	LikeSong struct {
+		db  LikeDatabase
+		pub Publisher
+	}
+
+	RateAlbum struct {
+		db  RatingDatabase
+		pub Publisher
+	}
+)
+
+func NewRegisterListener(db ListenerDatabase, pub Publisher) *RegisterListener {
+	return &RegisterListener{
+		db:  db,
+		pub: pub,
+	}
+}
+
+func NewFollowArtist(db FollowDatabase, pub Publisher) *FollowArtist {
+	return &FollowArtist{
+		db:  db,
+		pub: pub,
+	}
+}
+
+func NewLikeSong(db LikeDatabase, pub Publisher) *LikeSong {
+	return &LikeSong{
+		db:  db,
+		pub: pub,
+	}
+}
+
+func NewRateAlbum(db RatingDatabase, pub Publisher) *RateAlbum {
+	return &RateAlbum{
+		db:  db,
+		pub: pub,
+	}
+}
+
+func (rl RegisterListener) Execute(ctx context.Context, cmd RegisterListenerCommand) (song.Listener, error) {
+	listener := &song.Listener{
+		ID:    uuid.NewString(),
+		Name:  cmd.Name,
+		Email: cmd.Email,
+	}
+	if err := rl.db.CreateListener(ctx, listener); err != nil {
+		return song.Listener{}, err
+	}
+
+	m := event.NewAggregateMessage(listener.ID, message.NewListenerFromDomain(*listener))
+	if err := rl.pub.Publish(ctx, m, event.ListenerRegisteredEvent); err != nil {
+		return song.Listener{}, err
+	}
+
+	return *listener, nil
+}
+
+func (fa FollowArtist) Execute(ctx context.Context, cmd FollowArtistCommand) (song.Follow, error) {
+	if _, err := fa.db.GetListenerByID(ctx, cmd.ListenerID); err != nil {
+		return song.Follow{}, err
+	}
+
+	if _, err := fa.db.GetArtistByID(ctx, cmd.ArtistID); err != nil {
+		return song.Follow{}, err
+	}
+
+	follow := song.Follow{
+		ListenerID: cmd.ListenerID,
+		ArtistID:   cmd.ArtistID,
+		FollowedAt: time.Now().UTC(),
+	}
+	if err := fa.db.CreateFollow(ctx, follow); err != nil {
+		return song.Follow{}, err
+	}
+
+	m := event.NewAggregateMessage(follow.ListenerID, message.NewFollowFromDomain(follow))
+	if err := fa.pub.Publish(ctx, m, event.ArtistFollowedEvent); err != nil {
+		return song.Follow{}, err
+	}
+
+	return follow, nil
+}
+
+func (ls LikeSong) Execute(ctx context.Context, cmd LikeSongCommand) (song.Like, error) {
+	if _, err := ls.db.GetListenerByID(ctx, cmd.ListenerID); err != nil {
+		return song.Like{}, err
+	}
+
+	s, err := ls.db.GetSongByID(ctx, cmd.SongID)
+	if err != nil {
+		return song.Like{}, err
+	}
+
+	like := song.Like{
+		ListenerID: cmd.ListenerID,
+		Song:       s,
+		LikedAt:    time.Now().UTC(),
+	}
+	created, err := ls.db.CreateLike(ctx, like)
+	if err != nil {
+		return song.Like{}, err
+	}
+
+	if !created {
+		return like, nil
+	}
+
+	m := event.NewAggregateMessage(like.Song.ID, message.NewLikeFromDomain(like))
+	if err := ls.pub.Publish(ctx, m, event.SongLikedEvent); err != nil {
+		return song.Like{}, err
+	}
+
+	return like, nil
+}
+
+func (ra RateAlbum) Execute(ctx context.Context, cmd RateAlbumCommand) (song.AlbumRating, error) {
+	if cmd.Rating < song.MinRating || cmd.Rating > song.MaxRating {
+		return song.AlbumRating{}, fmt.Errorf("%w: rating must be between %d and %d",
+			InvalidCommandErr, song.MinRating, song.MaxRating)
+	}
+
+	if _, err := ra.db.GetListenerByID(ctx, cmd.ListenerID); err != nil {
+		return song.AlbumRating{}, err
+	}
+
+	if _, err := ra.db.GetAlbumByID(ctx, cmd.AlbumID); err != nil {
+		return song.AlbumRating{}, err
+	}
+
+	rating := song.AlbumRating{
+		ListenerID: cmd.ListenerID,
+		AlbumID:    cmd.AlbumID,
+		Rating:     cmd.Rating,
+		RatedAt:    time.Now().UTC(),
+	}
+	if err := ra.db.SaveAlbumRating(ctx, rating); err != nil {
+		return song.AlbumRating{}, err
+	}
+
+	m := event.NewAggregateMessage(rating.AlbumID, message.NewAlbumRatingFromDomain(rating))
+	if err := ra.pub.Publish(ctx, m, event.AlbumRatedEvent); err != nil {
+		return song.AlbumRating{}, err
+	}
+
+	return rating, nil
+}
@@ -0,0 +1,234 @@
+package command
+
+import (
+	"context"
+	"cqrs-sample/pkg/event"
+	"cqrs-sample/pkg/song"
+	"errors"
+	"testing"
+)
+
+func Test_Register_Listener_And_Play_Song(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	publisher := &fakePublisher{}
+	ctx := context.Background()
+	listenerRegister := NewRegisterListener(db, publisher)
+	songPlayer := NewPlaySong(db, publisher)
+
+	// Act
+	listener, err := listenerRegister.Execute(ctx, RegisterListenerCommand{
+		Name:  "Some Listener",
+		Email: "listener@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	knownErr := songPlayer.Execute(ctx, PlaySongCommand{
+		SongID:     "some-song",
+		ListenerID: listener.ID,
+	})
+	unknownErr := songPlayer.Execute(ctx, PlaySongCommand{
+		SongID:     "some-song",
+		ListenerID: "unknown-listener",
+	})
+
+	// Assert
+	if knownErr != nil {
+		t.Errorf("play by registered listener: got = %v, want = nil", knownErr)
+	}
+	if unknownErr == nil {
+		t.Error("play by unknown listener: got = nil, want error")
+	}
+}
+
+func Test_Register_Listener_With_Taken_Email(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	publisher := &recordingPublisher{}
+	ctx := context.Background()
+	listenerRegister := NewRegisterListener(db, publisher)
+	if _, err := listenerRegister.Execute(ctx, RegisterListenerCommand{
+		Name:  "Some Listener",
+		Email: "listener@example.com",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, err := listenerRegister.Execute(ctx, RegisterListenerCommand{
+		Name:  "Other Listener",
+		Email: "listener@example.com",
+	})
+
+	// Assert
+	if !errors.Is(err, song.AlreadyExistsErr) {
+		t.Errorf("taken email: got = %v, want = %v", err, song.AlreadyExistsErr)
+	}
+
+	if n := publisher.count(event.ListenerRegisteredEvent); n != 1 {
+		t.Errorf("listener registered events: got = %d, want = 1", n)
+	}
+}
+
+func Test_Like_Song_And_Rate_Album(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	publisher := &recordingPublisher{}
+	ctx := context.Background()
+
+	listener, err := NewRegisterListener(db, publisher).Execute(ctx, RegisterListenerCommand{
+		Name:  "Some Listener",
+		Email: "listener@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	artist, err := NewSubscribeArtist(db, publisher).Execute(ctx, SubscribeArtistCommand{
+		Name:   "Some Artist",
+		Gender: "Some Gender",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	album, err := NewCreateAlbum(db, publisher).Execute(ctx, CreateAlbumCommand{
+		Title:       "Some Album",
+		ArtistID:    artist.ID,
+		ReleaseYear: 2024,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewPublishSong(db, publisher).Execute(ctx, PublishSongCommand{
+		TrackNumber: 1,
+		Title:       "Some Song",
+		AlbumID:     album.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	liker := NewLikeSong(db, publisher)
+	rater := NewRateAlbum(db, publisher)
+
+	// Act
+	for i := 0; i < 2; i++ {
+		if _, err := liker.Execute(ctx, LikeSongCommand{ListenerID: listener.ID, SongID: s.ID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, outOfRangeErr := rater.Execute(ctx, RateAlbumCommand{ListenerID: listener.ID, AlbumID: album.ID, Rating: 6})
+	_, ratingErr := rater.Execute(ctx, RateAlbumCommand{ListenerID: listener.ID, AlbumID: album.ID, Rating: 4})
+
+	// Assert
+	if got := publisher.count(event.SongLikedEvent); got != 1 {
+		t.Errorf("%s published: got = %d, want = 1", event.SongLikedEvent, got)
+	}
+
+	if !errors.Is(outOfRangeErr, InvalidCommandErr) {
+		t.Errorf("out of range rating: got = %v, want = %v", outOfRangeErr, InvalidCommandErr)
+	}
+
+	if ratingErr != nil {
+		t.Errorf("valid rating: got = %v, want = nil", ratingErr)
+	}
+}
+
+type (
+	recordingPublisher struct {
+		events []event.Event
+	}
+)
+
+func (r *recordingPublisher) Publish(_ context.Context, _ event.Message, e event.Event) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *recordingPublisher) count(e event.Event) int {
+	var n int
+	for _, published := range r.events {
+		if published == e {
+			n++
+		}
+	}
+
+	return n
+}
+
+func Test_Follow_Artist(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	publisher := &recordingPublisher{}
+	ctx := context.Background()
+
+	listener, err := NewRegisterListener(db, publisher).Execute(ctx, RegisterListenerCommand{
+		Name:  "Some Listener",
+		Email: "listener@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	artist, err := NewSubscribeArtist(db, publisher).Execute(ctx, SubscribeArtistCommand{
+		Name:   "Some Artist",
+		Gender: "Some Gender",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	follower := NewFollowArtist(db, publisher)
+
+	// Act
+	follow, err := follower.Execute(ctx, FollowArtistCommand{ListenerID: listener.ID, ArtistID: artist.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, refollowErr := follower.Execute(ctx, FollowArtistCommand{ListenerID: listener.ID, ArtistID: artist.ID})
+	_, unknownArtistErr := follower.Execute(ctx, FollowArtistCommand{ListenerID: listener.ID, ArtistID: "unknown"})
+	_, unknownListenerErr := follower.Execute(ctx, FollowArtistCommand{ListenerID: "unknown", ArtistID: artist.ID})
+
+	// Assert
+	if follow.ListenerID != listener.ID || follow.ArtistID != artist.ID || follow.FollowedAt.IsZero() {
+		t.Errorf("follow: got = %+v", follow)
+	}
+
+	if refollowErr != nil {
+		t.Errorf("refollow: got = %v, want = nil", refollowErr)
+	}
+
+	if !errors.Is(unknownArtistErr, song.NotFoundErr) {
+		t.Errorf("unknown artist: got = %v, want = %v", unknownArtistErr, song.NotFoundErr)
+	}
+
+	if !errors.Is(unknownListenerErr, song.NotFoundErr) {
+		t.Errorf("unknown listener: got = %v, want = %v", unknownListenerErr, song.NotFoundErr)
+	}
+
+	if got := publisher.count(event.ArtistFollowedEvent); got != 2 {
+		t.Errorf("%s published: got = %d, want = 2", event.ArtistFollowedEvent, got)
+	}
+}
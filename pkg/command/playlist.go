@@ -0,0 +1,229 @@
+package command
+
+import (
+	"context"
+	"cqrs-sample/pkg/event"
+	"cqrs-sample/pkg/message"
+	"cqrs-sample/pkg/song"
+	"fmt"
+	"github.com/google/uuid"
+)
+
+type (
+	PlaylistDatabase interface {
+		ListenerDatabase
+		GetSongByID(ctx context.Context, id string) (song.Song, error)
+		CreatePlaylist(ctx context.Context, playlist *song.Playlist) error
+		GetPlaylistByID(ctx context.Context, id string) (song.Playlist, error)
+		SavePlaylist(ctx context.Context, playlist *song.Playlist) error
+	}
+
+	CreatePlaylistCommand struct {
+		Name       string
+		ListenerID string
+	}
+
+	RenamePlaylistCommand struct {
+		PlaylistID string
+		Name       string
+		Version    int
+	}
+
+	AddSongToPlaylistCommand struct {
+		PlaylistID string
+		SongID     string
+		Version    int
+	}
+
+	RemoveSongFromPlaylistCommand struct {
+		PlaylistID string
+		SongID     string
+		Version    int
+	}
+
+	ReorderPlaylistCommand struct {
+		PlaylistID string
+		SongIDs    []string
+		Version    int
+	}
+
+	CreatePlaylist struct {
+		db  PlaylistDatabase
+		pub Publisher
+	}
+
+	RenamePlaylist struct {
+		db  PlaylistDatabase
+		pub Publisher
+	}
+
+	AddSongToPlaylist struct {
+		db  PlaylistDatabase
+		pub Publisher
+	}
+
+	RemoveSongFromPlaylist struct {
+		db  PlaylistDatabase
+		pub Publisher
+	}
+
+	ReorderPlaylist struct {
+		db  PlaylistDatabase
+		pub Publisher
+	}
+)
+
+func NewCreatePlaylist(db PlaylistDatabase, pub Publisher) *CreatePlaylist {
+	return &CreatePlaylist{
+		db:  db,
+		pub: pub,
+	}
+}
+
+func NewRenamePlaylist(db PlaylistDatabase, pub Publisher) *RenamePlaylist {
+	return &RenamePlaylist{
+		db:  db,
+		pub: pub,
+	}
+}
+
+func NewAddSongToPlaylist(db PlaylistDatabase, pub Publisher) *AddSongToPlaylist {
+	return &AddSongToPlaylist{
+		db:  db,
+		pub: pub,
+	}
+}
+
+func NewRemoveSongFromPlaylist(db PlaylistDatabase, pub Publisher) *RemoveSongFromPlaylist {
+	return &RemoveSongFromPlaylist{
+		db:  db,
+		pub: pub,
+	}
+}
+
+func NewReorderPlaylist(db PlaylistDatabase, pub Publisher) *ReorderPlaylist {
+	return &ReorderPlaylist{
+		db:  db,
+		pub: pub,
+	}
+}
+
+func (cp CreatePlaylist) Execute(ctx context.Context, cmd CreatePlaylistCommand) (song.Playlist, error) {
+	if cmd.Name == "" {
+		return song.Playlist{}, fmt.Errorf("%w: playlist name is required", InvalidCommandErr)
+	}
+
+	if _, err := cp.db.GetListenerByID(ctx, cmd.ListenerID); err != nil {
+		return song.Playlist{}, err
+	}
+
+	playlist := &song.Playlist{
+		ID:         uuid.NewString(),
+		Name:       cmd.Name,
+		ListenerID: cmd.ListenerID,
+	}
+	if err := cp.db.CreatePlaylist(ctx, playlist); err != nil {
+		return song.Playlist{}, err
+	}
+
+	return publishPlaylist(ctx, cp.pub, *playlist, event.PlaylistCreatedEvent)
+}
+
+func (rp RenamePlaylist) Execute(ctx context.Context, cmd RenamePlaylistCommand) (song.Playlist, error) {
+	if cmd.Name == "" {
+		return song.Playlist{}, fmt.Errorf("%w: playlist name is required", InvalidCommandErr)
+	}
+
+	playlist, err := rp.db.GetPlaylistByID(ctx, cmd.PlaylistID)
+	if err != nil {
+		return song.Playlist{}, err
+	}
+
+	if err := checkVersion(playlist.Version, cmd.Version); err != nil {
+		return song.Playlist{}, err
+	}
+
+	playlist.Rename(cmd.Name)
+	if err := rp.db.SavePlaylist(ctx, &playlist); err != nil {
+		return song.Playlist{}, err
+	}
+
+	return publishPlaylist(ctx, rp.pub, playlist, event.PlaylistRenamedEvent)
+}
+
+func (ap AddSongToPlaylist) Execute(ctx context.Context, cmd AddSongToPlaylistCommand) (song.Playlist, error) {
+	playlist, err := ap.db.GetPlaylistByID(ctx, cmd.PlaylistID)
+	if err != nil {
+		return song.Playlist{}, err
+	}
+
+	if err := checkVersion(playlist.Version, cmd.Version); err != nil {
+		return song.Playlist{}, err
+	}
+
+	s, err := ap.db.GetSongByID(ctx, cmd.SongID)
+	if err != nil {
+		return song.Playlist{}, err
+	}
+
+	if err := playlist.AddSong(s); err != nil {
+		return song.Playlist{}, fmt.Errorf("%w: %w", InvalidCommandErr, err)
+	}
+
+	if err := ap.db.SavePlaylist(ctx, &playlist); err != nil {
+		return song.Playlist{}, err
+	}
+
+	return publishPlaylist(ctx, ap.pub, playlist, event.PlaylistSongAddedEvent)
+}
+
+func (rp RemoveSongFromPlaylist) Execute(ctx context.Context, cmd RemoveSongFromPlaylistCommand) (song.Playlist, error) {
+	playlist, err := rp.db.GetPlaylistByID(ctx, cmd.PlaylistID)
+	if err != nil {
+		return song.Playlist{}, err
+	}
+
+	if err := checkVersion(playlist.Version, cmd.Version); err != nil {
+		return song.Playlist{}, err
+	}
+
+	if err := playlist.RemoveSong(cmd.SongID); err != nil {
+		return song.Playlist{}, fmt.Errorf("%w: %w", InvalidCommandErr, err)
+	}
+
+	if err := rp.db.SavePlaylist(ctx, &playlist); err != nil {
+		return song.Playlist{}, err
+	}
+
+	return publishPlaylist(ctx, rp.pub, playlist, event.PlaylistSongRemovedEvent)
+}
+
+func (rp ReorderPlaylist) Execute(ctx context.Context, cmd ReorderPlaylistCommand) (song.Playlist, error) {
+	playlist, err := rp.db.GetPlaylistByID(ctx, cmd.PlaylistID)
+	if err != nil {
+		return song.Playlist{}, err
+	}
+
+	if err := checkVersion(playlist.Version, cmd.Version); err != nil {
+		return song.Playlist{}, err
+	}
+
+	if err := playlist.Reorder(cmd.SongIDs); err != nil {
+		return song.Playlist{}, fmt.Errorf("%w: %w", InvalidCommandErr, err)
+	}
+
+	if err := rp.db.SavePlaylist(ctx, &playlist); err != nil {
+		return song.Playlist{}, err
+	}
+
+	return publishPlaylist(ctx, rp.pub, playlist, event.PlaylistReorderedEvent)
+}
+
+func publishPlaylist(ctx context.Context, pub Publisher, playlist song.Playlist, e event.Event) (song.Playlist, error) {
+	m := event.NewAggregateMessage(playlist.ID, message.NewPlaylistFromDomain(playlist))
+	if err := pub.Publish(ctx, m, e); err != nil {
+		return song.Playlist{}, err
+	}
+
+	return playlist, nil
+}
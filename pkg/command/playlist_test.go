@@ -0,0 +1,182 @@
+package command
+
+import (
+	"context"
+	"cqrs-sample/pkg/song"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_Playlist_Add_Reorder_And_Remove_Songs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	publisher := &fakePublisher{}
+	ctx := context.Background()
+
+	listener, err := NewRegisterListener(db, publisher).Execute(ctx, RegisterListenerCommand{
+		Name:  "Some Listener",
+		Email: "listener@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	artist, err := NewSubscribeArtist(db, publisher).Execute(ctx, SubscribeArtistCommand{
+		Name:   "Some Artist",
+		Gender: "Some Gender",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	album, err := NewCreateAlbum(db, publisher).Execute(ctx, CreateAlbumCommand{
+		Title:       "Some Album",
+		ArtistID:    artist.ID,
+		ReleaseYear: 2024,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	songPublisher := NewPublishSong(db, publisher)
+	first, err := songPublisher.Execute(ctx, PublishSongCommand{TrackNumber: 1, Title: "First", AlbumID: album.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := songPublisher.Execute(ctx, PublishSongCommand{TrackNumber: 2, Title: "Second", AlbumID: album.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adder := NewAddSongToPlaylist(db, publisher)
+
+	// Act
+	playlist, err := NewCreatePlaylist(db, publisher).Execute(ctx, CreatePlaylistCommand{
+		Name:       "Some Playlist",
+		ListenerID: listener.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{first.ID, second.ID} {
+		playlist, err = adder.Execute(ctx, AddSongToPlaylistCommand{PlaylistID: playlist.ID, SongID: id, Version: playlist.Version})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, duplicateErr := adder.Execute(ctx, AddSongToPlaylistCommand{PlaylistID: playlist.ID, SongID: first.ID, Version: AnyVersion})
+	_, staleErr := adder.Execute(ctx, AddSongToPlaylistCommand{PlaylistID: playlist.ID, SongID: first.ID, Version: playlist.Version - 1})
+
+	playlist, err = NewReorderPlaylist(db, publisher).Execute(ctx, ReorderPlaylistCommand{
+		PlaylistID: playlist.ID,
+		SongIDs:    []string{second.ID, first.ID},
+		Version:    playlist.Version,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	playlist, err = NewRemoveSongFromPlaylist(db, publisher).Execute(ctx, RemoveSongFromPlaylistCommand{
+		PlaylistID: playlist.ID,
+		SongID:     first.ID,
+		Version:    playlist.Version,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	if !errors.Is(duplicateErr, InvalidCommandErr) {
+		t.Errorf("duplicate song: got = %v, want = %v", duplicateErr, InvalidCommandErr)
+	}
+
+	if !errors.Is(staleErr, song.VersionConflictErr) {
+		t.Errorf("stale version: got = %v, want = %v", staleErr, song.VersionConflictErr)
+	}
+
+	stored, err := db.GetPlaylistByID(ctx, playlist.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotIDs := make([]string, len(stored.Songs))
+	for i, s := range stored.Songs {
+		gotIDs[i] = s.ID
+	}
+
+	wantIDs := []string{second.ID}
+	if !reflect.DeepEqual(gotIDs, wantIDs) {
+		t.Errorf("\n\tgot = %+v\n\twant= %+v", gotIDs, wantIDs)
+	}
+
+	if stored.Version != 4 {
+		t.Errorf("version: got = %d, want = 4", stored.Version)
+	}
+}
+
+func Test_Playlist_Stale_Save_Is_Rejected(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	publisher := &fakePublisher{}
+	ctx := context.Background()
+
+	listener, err := NewRegisterListener(db, publisher).Execute(ctx, RegisterListenerCommand{
+		Name:  "Some Listener",
+		Email: "listener@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	playlist, err := NewCreatePlaylist(db, publisher).Execute(ctx, CreatePlaylistCommand{
+		Name:       "Some Playlist",
+		ListenerID: listener.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale, err := db.GetPlaylistByID(ctx, playlist.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	renamed, err := NewRenamePlaylist(db, publisher).Execute(ctx, RenamePlaylistCommand{
+		PlaylistID: playlist.ID,
+		Name:       "Renamed",
+		Version:    playlist.Version,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale.Rename("Stale")
+	staleErr := db.SavePlaylist(ctx, &stale)
+
+	// Assert
+	if !errors.Is(staleErr, song.VersionConflictErr) {
+		t.Errorf("stale save: got = %v, want = %v", staleErr, song.VersionConflictErr)
+	}
+
+	saved, err := db.GetPlaylistByID(ctx, playlist.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if saved.Name != "Renamed" || saved.Version != renamed.Version {
+		t.Errorf("saved: got = %s@%d, want = Renamed@%d", saved.Name, saved.Version, renamed.Version)
+	}
+}
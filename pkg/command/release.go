@@ -0,0 +1,124 @@
+package command
+
+import (
+	"context"
+	"cqrs-sample/pkg/event"
+	"cqrs-sample/pkg/message"
+	"cqrs-sample/pkg/song"
+	"fmt"
+	"time"
+)
+
+type (
+	ScheduleDatabase interface {
+		GetAlbumByID(ctx context.Context, id string) (song.Album, error)
+		GetArtistByID(ctx context.Context, id string) (song.Artist, error)
+		ScheduleAlbum(ctx context.Context, album *song.Album) error
+	}
+
+	ReleaseDatabase interface {
+		GetAlbumsDueForRelease(ctx context.Context, now time.Time) ([]song.Album, error)
+		MarkAlbumPublished(ctx context.Context, album *song.Album) (bool, error)
+		MarkAlbumReleaseAnnounced(ctx context.Context, album song.Album) error
+	}
+
+	ScheduleAlbumCommand struct {
+		AlbumID     string
+		ReleaseDate time.Time
+		Version     int
+	}
+
+	ScheduleAlbum struct {
+		db  ScheduleDatabase
+		pub Publisher
+		now func() time.Time
+	}
+
+	ReleaseDueAlbums struct {
+		db  ReleaseDatabase
+		pub Publisher
+	}
+)
+
+func NewScheduleAlbum(db ScheduleDatabase, pub Publisher) *ScheduleAlbum {
+	return &ScheduleAlbum{
+		db:  db,
+		pub: pub,
+		now: time.Now,
+	}
+}
+
+func NewReleaseDueAlbums(db ReleaseDatabase, pub Publisher) *ReleaseDueAlbums {
+	return &ReleaseDueAlbums{
+		db:  db,
+		pub: pub,
+	}
+}
+
+func (sa ScheduleAlbum) Execute(ctx context.Context, cmd ScheduleAlbumCommand) (song.Album, error) {
+	album, err := sa.db.GetAlbumByID(ctx, cmd.AlbumID)
+	if err != nil {
+		return song.Album{}, err
+	}
+
+	if err := checkVersion(album.Version, cmd.Version); err != nil {
+		return song.Album{}, err
+	}
+
+	artist, err := sa.db.GetArtistByID(ctx, album.Artist.ID)
+	if err != nil {
+		return song.Album{}, err
+	}
+
+	album.Artist = artist
+	if err := album.Schedule(cmd.ReleaseDate.UTC(), sa.now()); err != nil {
+		return song.Album{}, fmt.Errorf("%w: %w", InvalidCommandErr, err)
+	}
+
+	if err := sa.db.ScheduleAlbum(ctx, &album); err != nil {
+		return song.Album{}, err
+	}
+
+	m := event.NewAggregateMessage(album.ID, message.NewAlbumFromDomain(album))
+	if err := sa.pub.Publish(ctx, m, event.AlbumScheduledEvent); err != nil {
+		return song.Album{}, err
+	}
+
+	return album, nil
+}
+
+func (ra ReleaseDueAlbums) Execute(ctx context.Context, now time.Time) ([]song.Album, error) {
+	albums, err := ra.db.GetAlbumsDueForRelease(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var released []song.Album
+	for _, album := range albums {
+		// mark before publishing so an album rescheduled meanwhile is never
+		// announced, the release stays pending until the event is out
+		if album.Status == song.ScheduledStatus {
+			ok, err := ra.db.MarkAlbumPublished(ctx, &album)
+			if err != nil {
+				return released, err
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		m := event.NewAggregateMessage(album.ID, message.NewAlbumFromDomain(album))
+		if err := ra.pub.Publish(ctx, m, event.AlbumPublishedEvent); err != nil {
+			return released, err
+		}
+
+		if err := ra.db.MarkAlbumReleaseAnnounced(ctx, album); err != nil {
+			return released, err
+		}
+
+		released = append(released, album)
+	}
+
+	return released, nil
+}
@@ -0,0 +1,255 @@
+package command
+
+import (
+	"context"
+	"cqrs-sample/pkg/event"
+	"cqrs-sample/pkg/song"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_Schedule_And_Release_Album(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	publisher := &recordingPublisher{}
+	ctx := context.Background()
+	releaseDate := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	artist, err := NewSubscribeArtist(db, publisher).Execute(ctx, SubscribeArtistCommand{Name: "Some Artist"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	album, err := NewCreateAlbum(db, publisher).Execute(ctx, CreateAlbumCommand{
+		Title:    "Some Album",
+		ArtistID: artist.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scheduler := NewScheduleAlbum(db, publisher)
+	scheduler.now = func() time.Time { return releaseDate.AddDate(0, -1, 0) }
+
+	// Act
+	_, pastErr := scheduler.Execute(ctx, ScheduleAlbumCommand{
+		AlbumID:     album.ID,
+		ReleaseDate: releaseDate.AddDate(0, -2, 0),
+		Version:     album.Version,
+	})
+
+	scheduled, err := scheduler.Execute(ctx, ScheduleAlbumCommand{
+		AlbumID:     album.ID,
+		ReleaseDate: releaseDate,
+		Version:     album.Version,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, staleErr := scheduler.Execute(ctx, ScheduleAlbumCommand{
+		AlbumID:     album.ID,
+		ReleaseDate: releaseDate.AddDate(0, 1, 0),
+		Version:     album.Version,
+	})
+
+	_, notDraftErr := NewPublishSong(db, publisher).Execute(ctx, PublishSongCommand{
+		TrackNumber: 1,
+		Title:       "Late Song",
+		AlbumID:     album.ID,
+	})
+
+	releaser := NewReleaseDueAlbums(db, publisher)
+	early, err := releaser.Execute(ctx, releaseDate.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	released, err := releaser.Execute(ctx, releaseDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := releaser.Execute(ctx, releaseDate.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	if !errors.Is(pastErr, song.PastReleaseErr) {
+		t.Errorf("past release date: got = %v, want = %v", pastErr, song.PastReleaseErr)
+	}
+
+	if scheduled.Status != song.ScheduledStatus {
+		t.Errorf("scheduled status: got = %q, want = %q", scheduled.Status, song.ScheduledStatus)
+	}
+
+	if !errors.Is(staleErr, song.VersionConflictErr) {
+		t.Errorf("stale schedule: got = %v, want = %v", staleErr, song.VersionConflictErr)
+	}
+
+	if !errors.Is(notDraftErr, InvalidCommandErr) {
+		t.Errorf("song on scheduled album: got = %v, want = %v", notDraftErr, InvalidCommandErr)
+	}
+
+	if len(early) != 0 || len(again) != 0 {
+		t.Errorf("unexpected releases: early = %d, again = %d", len(early), len(again))
+	}
+
+	if len(released) != 1 || released[0].ID != album.ID || released[0].Status != song.PublishedStatus {
+		t.Fatalf("released = %+v", released)
+	}
+
+	stored, err := db.GetAlbumByID(ctx, album.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Status != song.PublishedStatus || stored.Version != 3 {
+		t.Errorf("stored: status = %q, version = %d", stored.Status, stored.Version)
+	}
+
+	if n := publisher.count(event.AlbumPublishedEvent); n != 1 {
+		t.Errorf("album published events: got = %d, want = 1", n)
+	}
+
+	if _, err := scheduler.Execute(ctx, ScheduleAlbumCommand{
+		AlbumID:     album.ID,
+		ReleaseDate: releaseDate,
+		Version:     stored.Version,
+	}); !errors.Is(err, song.AlbumPublishedErr) {
+		t.Errorf("reschedule published: got = %v, want = %v", err, song.AlbumPublishedErr)
+	}
+}
+
+func Test_Release_Album_Is_Retried_When_Publish_Fails(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	// Arrange
+	db := setupDatabase(t)
+	publisher := &recordingPublisher{}
+	ctx := context.Background()
+	releaseDate := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	artist, err := NewSubscribeArtist(db, publisher).Execute(ctx, SubscribeArtistCommand{Name: "Some Artist"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	album, err := NewCreateAlbum(db, publisher).Execute(ctx, CreateAlbumCommand{
+		Title:    "Some Album",
+		ArtistID: artist.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scheduler := NewScheduleAlbum(db, publisher)
+	scheduler.now = func() time.Time { return releaseDate.AddDate(0, -1, 0) }
+	if _, err := scheduler.Execute(ctx, ScheduleAlbumCommand{
+		AlbumID:     album.ID,
+		ReleaseDate: releaseDate,
+		Version:     album.Version,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	failing := &failingPublisher{failures: 1}
+
+	// Act
+	_, failedErr := NewReleaseDueAlbums(db, failing).Execute(ctx, releaseDate)
+	released, err := NewReleaseDueAlbums(db, failing).Execute(ctx, releaseDate)
+
+	// Assert
+	if failedErr == nil {
+		t.Error("failed publish: got = nil, want error")
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(released) != 1 || released[0].Status != song.PublishedStatus {
+		t.Fatalf("released = %+v", released)
+	}
+
+	if n := failing.count(event.AlbumPublishedEvent); n != 1 {
+		t.Errorf("album published events: got = %d, want = 1", n)
+	}
+}
+
+type fakeReleaseDatabase struct {
+	due       []song.Album
+	marked    bool
+	announced []string
+}
+
+func (f *fakeReleaseDatabase) GetAlbumsDueForRelease(context.Context, time.Time) ([]song.Album, error) {
+	return f.due, nil
+}
+
+func (f *fakeReleaseDatabase) MarkAlbumPublished(_ context.Context, album *song.Album) (bool, error) {
+	if !f.marked {
+		return false, nil
+	}
+
+	album.Status = song.PublishedStatus
+	album.Version++
+	return true, nil
+}
+
+func (f *fakeReleaseDatabase) MarkAlbumReleaseAnnounced(_ context.Context, album song.Album) error {
+	f.announced = append(f.announced, album.ID)
+	return nil
+}
+
+func Test_Release_Album_Rescheduled_Meanwhile_Is_Not_Announced(t *testing.T) {
+	// Arrange
+	db := &fakeReleaseDatabase{due: []song.Album{{ID: "1", Status: song.ScheduledStatus, Version: 2}}}
+	publisher := &recordingPublisher{}
+
+	// Act
+	released, err := NewReleaseDueAlbums(db, publisher).Execute(context.Background(), time.Now())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("err: got = %v, want = nil", err)
+	}
+
+	if len(released) != 0 || len(db.announced) != 0 {
+		t.Errorf("released = %+v, announced = %v", released, db.announced)
+	}
+
+	if n := publisher.count(event.AlbumPublishedEvent); n != 0 {
+		t.Errorf("album published events: got = %d, want = 0", n)
+	}
+}
+
+func Test_Release_Pending_Album_Is_Announced_Without_Marking_Again(t *testing.T) {
+	// Arrange
+	db := &fakeReleaseDatabase{due: []song.Album{{ID: "1", Status: song.PublishedStatus, Version: 3}}}
+	publisher := &recordingPublisher{}
+
+	// Act
+	released, err := NewReleaseDueAlbums(db, publisher).Execute(context.Background(), time.Now())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("err: got = %v, want = nil", err)
+	}
+
+	if len(released) != 1 || released[0].Version != 3 {
+		t.Errorf("released = %+v", released)
+	}
+
+	if n := publisher.count(event.AlbumPublishedEvent); n != 1 {
+		t.Errorf("album published events: got = %d, want = 1", n)
+	}
+}
@@ -6,10 +6,37 @@ import (
 )
 
 const (
-	ArtistSubscribedEvent Event = "ARTIST_SUBSCRIBED"
-	AlbumPublishedEvent   Event = "ALBUM_PUBLISHED"
-	SongPublishedEvent    Event = "SONG_PUBLISHED"
-	SongPlayedEvent       Event = "SONG_PLAYED"
+	ArtistSubscribedEvent    Event = "ARTIST_SUBSCRIBED"
+	AlbumCreatedEvent        Event = "ALBUM_CREATED"
+	AlbumScheduledEvent      Event = "ALBUM_SCHEDULED"
+	AlbumPublishedEvent      Event = "ALBUM_PUBLISHED"
+	SongPublishedEvent       Event = "SONG_PUBLISHED"
+	SongPlayedEvent          Event = "SONG_PLAYED"
+	ListenerRegisteredEvent  Event = "LISTENER_REGISTERED"
+	PlaylistCreatedEvent     Event = "PLAYLIST_CREATED"
+	PlaylistRenamedEvent     Event = "PLAYLIST_RENAMED"
+	PlaylistSongAddedEvent   Event = "PLAYLIST_SONG_ADDED"
+	PlaylistSongRemovedEvent Event = "PLAYLIST_SONG_REMOVED"
+	PlaylistReorderedEvent   Event = "PLAYLIST_REORDERED"
+	ArtistFollowedEvent      Event = "ARTIST_FOLLOWED"
+	SongLikedEvent           Event = "SONG_LIKED"
+	AlbumRatedEvent          Event = "ALBUM_RATED"
+	ArtistMergedEvent        Event = "ARTIST_MERGED"
+	CacheInvalidatedEvent    Event = "CACHE_INVALIDATED"
+)
+
+const (
+	// PublishedAtHeader carries the time an event left the command side,
+	// which projections record as their checkpoint.
+	PublishedAtHeader = "published_at"
+
+	// AggregateIDHeader carries the artist, album, song, listener or
+	// playlist an event belongs to, so consumers can keep its events in order.
+	AggregateIDHeader = "aggregate_id"
+
+	// RequestIDHeader carries the ID of the HTTP request that caused an
+	// event, so its processing can be traced across services.
+	RequestIDHeader = "request_id"
 )
 
 var (
@@ -29,6 +56,10 @@ func NewMessage(payload any) Message {
 	return NewMessageWithHeaders(payload, nil)
 }
 
+func NewAggregateMessage(aggregateID string, payload any) Message {
+	return NewMessageWithHeaders(payload, map[string]interface{}{AggregateIDHeader: aggregateID})
+}
+
 func NewMessageWithHeaders(payload any, headers map[string]interface{}) Message {
 	body, _ := json.Marshal(payload)
 
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"cqrs-sample/pkg/event"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const checkpointInterval = time.Second
+
+type (
+	QueueHandler interface {
+		Handle(ctx context.Context, body []byte, headers map[string]interface{}) error
+	}
+
+	CheckpointDatabase interface {
+		RecordCheckpoint(ctx context.Context, projection string, eventAt, processedAt time.Time, processed int64) error
+		RecordProjectionError(ctx context.Context, projection, message string, failedAt time.Time) error
+	}
+
+	BacklogDatabase interface {
+		RecordProjectionBacklog(ctx context.Context, projection string, pending int, sampledAt time.Time) error
+	}
+
+	QueueCounter interface {
+		Pending(ctx context.Context, queue string) (int, error)
+	}
+
+	// Checkpointed records when the events applied by next were published
+	// and applied, writing at most once per checkpointInterval.
+	Checkpointed struct {
+		projection string
+		next       QueueHandler
+		db         CheckpointDatabase
+
+		mu        sync.Mutex
+		scheduled bool
+		eventAt   time.Time
+		processed int64
+	}
+
+	// SampleBacklog stores how many events each projection queue still has
+	// to apply.
+	SampleBacklog struct {
+		queues  QueueCounter
+		db      BacklogDatabase
+		tracked []string
+	}
+)
+
+func NewCheckpointed(projection string, next QueueHandler, db CheckpointDatabase) *Checkpointed {
+	return &Checkpointed{
+		projection: projection,
+		next:       next,
+		db:         db,
+	}
+}
+
+func NewSampleBacklog(queues QueueCounter, db BacklogDatabase, tracked ...string) *SampleBacklog {
+	return &SampleBacklog{
+		queues:  queues,
+		db:      db,
+		tracked: tracked,
+	}
+}
+
+func (c *Checkpointed) Handle(ctx context.Context, body []byte, headers map[string]interface{}) error {
+	err := c.next.Handle(ctx, body, headers)
+	now := time.Now().UTC()
+	if err != nil {
+		if recordErr := c.db.RecordProjectionError(ctx, c.projection, err.Error(), now); recordErr != nil {
+			slog.ErrorContext(ctx, "checkpoint failed", "projection", c.projection, "error", recordErr)
+		}
+
+		return err
+	}
+
+	eventAt, ok := headers[event.PublishedAtHeader].(time.Time)
+	if !ok {
+		eventAt = now
+	}
+
+	c.track(ctx, eventAt.UTC())
+	return nil
+}
+
+func (c *Checkpointed) track(ctx context.Context, eventAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if eventAt.After(c.eventAt) {
+		c.eventAt = eventAt
+	}
+	c.processed++
+
+	if !c.scheduled {
+		c.scheduled = true
+		ctx = context.WithoutCancel(ctx)
+		time.AfterFunc(checkpointInterval, func() {
+			c.Flush(ctx)
+		})
+	}
+}
+
+// Flush writes the events counted since the last checkpoint.
+func (c *Checkpointed) Flush(ctx context.Context) {
+	c.mu.Lock()
+	eventAt, processed := c.eventAt, c.processed
+	c.eventAt, c.processed, c.scheduled = time.Time{}, 0, false
+	c.mu.Unlock()
+
+	if processed == 0 {
+		return
+	}
+
+	if err := c.db.RecordCheckpoint(ctx, c.projection, eventAt, time.Now().UTC(), processed); err != nil {
+		slog.ErrorContext(ctx, "checkpoint failed", "projection", c.projection, "error", err)
+	}
+}
+
+func (sb SampleBacklog) Execute(ctx context.Context) error {
+	now := time.Now().UTC()
+	for _, queue := range sb.tracked {
+		pending, err := sb.queues.Pending(ctx, queue)
+		if err != nil {
+			return err
+		}
+
+		if err := sb.db.RecordProjectionBacklog(ctx, queue, pending, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
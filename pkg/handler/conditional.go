@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"cqrs-sample/pkg/command"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	cacheControlWriter struct {
+		http.ResponseWriter
+		value       string
+		wroteHeader bool
+	}
+)
+
+// CacheControl sets the given Cache-Control directives on the 200 and 304
+// responses of the routes it wraps, so errors are never cached. An empty
+// value leaves the header untouched.
+func CacheControl(value string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if value == "" {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, value: value}, r)
+		})
+	}
+}
+
+func (cw *cacheControlWriter) WriteHeader(statusCode int) {
+	if !cw.wroteHeader && (statusCode == http.StatusOK || statusCode == http.StatusNotModified) {
+		cw.Header().Set("Cache-Control", cw.value)
+	}
+
+	cw.wroteHeader = true
+	cw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (cw *cacheControlWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	return cw.ResponseWriter.Write(b)
+}
+
+// writeConditionalResponse writes output with a strong ETag made of the
+// document version and a hash of the body, so projected counters such as
+// plays or ratings also invalidate it, and answers 304 Not Modified when the
+// request preconditions show the client already holds this representation.
+func writeConditionalResponse(w http.ResponseWriter, r *http.Request, output any, version int, modified time.Time) {
+	body, err := json.Marshal(output)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	tag := strconv.Quote(fmt.Sprintf("%d-%s", version, hex.EncodeToString(sum[:8])))
+	w.Header().Set("ETag", tag)
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, tag, modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// notModified evaluates If-None-Match and, only when it is absent,
+// If-Modified-Since as described in RFC 9110.
+func notModified(r *http.Request, tag string, modified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == tag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || modified.IsZero() {
+		return false
+	}
+
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// etag renders a resource version as a strong entity tag.
+func etag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// requireVersion reads the version a write expects from If-Match. Writes
+// must be conditional, so it answers 428 Precondition Required when the
+// header is missing and 412 Precondition Failed when it can never match.
+func requireVersion(w http.ResponseWriter, r *http.Request) (int, bool) {
+	if r.Header.Get("If-Match") == "" {
+		http.Error(w, http.StatusText(http.StatusPreconditionRequired), http.StatusPreconditionRequired)
+		return 0, false
+	}
+
+	version, ok := ifMatch(r)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+		return 0, false
+	}
+
+	return version, true
+}
+
+// ifMatch reads the version the client expects from the If-Match header. It
+// accepts both the bare version tags returned by commands and the tags of
+// the query API, whose version precedes the content hash, and * for any
+// version of an existing resource. If-Match uses the strong comparison, so a
+// weak tag never matches.
+func ifMatch(r *http.Request) (int, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "*" {
+		return command.AnyVersion, true
+	}
+
+	value, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, false
+	}
+
+	value, _, _ = strings.Cut(value, "-")
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return version, true
+}
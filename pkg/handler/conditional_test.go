@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"cqrs-sample/pkg/command"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Cache_Control_Is_Set_Only_On_Cacheable_Responses(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{status: http.StatusOK, want: "max-age=60"},
+		{status: http.StatusNotModified, want: "max-age=60"},
+		{status: http.StatusNotFound, want: ""},
+		{status: http.StatusInternalServerError, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			// Arrange
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			})
+			w := httptest.NewRecorder()
+
+			// Act
+			CacheControl("max-age=60")(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/song/1", nil))
+
+			// Assert
+			if got := w.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("Cache-Control: got = %q, want = %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Not_Modified(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "no preconditions", want: false},
+		{name: "matching tag", headers: map[string]string{"If-None-Match": `"1-a", "2-b"`}, want: true},
+		{name: "weak matching tag", headers: map[string]string{"If-None-Match": `W/"2-b"`}, want: true},
+		{name: "any tag", headers: map[string]string{"If-None-Match": "*"}, want: true},
+		{name: "other tag", headers: map[string]string{"If-None-Match": `"1-a"`}, want: false},
+		{
+			name: "tag wins over date",
+			headers: map[string]string{
+				"If-None-Match":     `"1-a"`,
+				"If-Modified-Since": modified.Format(http.TimeFormat),
+			},
+			want: false,
+		},
+		{name: "unmodified since", headers: map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, want: true},
+		{name: "modified since", headers: map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/song/1", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			if got := notModified(r, `"2-b"`, modified.Add(300*time.Millisecond)); got != tt.want {
+				t.Errorf("notModified: got = %t, want = %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_If_Match(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int
+		ok      bool
+	}{
+		{header: `"3"`, version: 3, ok: true},
+		{header: `"3-0a1b2c"`, version: 3, ok: true},
+		{header: `*`, version: command.AnyVersion, ok: true},
+		{header: `W/"3"`, ok: false},
+		{header: `3`, ok: false},
+		{header: ``, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/album/1/schedule", nil)
+			r.Header.Set("If-Match", tt.header)
+
+			version, ok := ifMatch(r)
+			if version != tt.version || ok != tt.ok {
+				t.Errorf("ifMatch: got = %d, %t, want = %d, %t", version, ok, tt.version, tt.ok)
+			}
+		})
+	}
+}
+
+func Test_Require_Version(t *testing.T) {
+	tests := []struct {
+		header string
+		status int
+		ok     bool
+	}{
+		{header: `"3"`, status: http.StatusOK, ok: true},
+		{header: `*`, status: http.StatusOK, ok: true},
+		{header: `W/"3"`, status: http.StatusPreconditionFailed, ok: false},
+		{header: ``, status: http.StatusPreconditionRequired, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/playlist/1", nil)
+			r.Header.Set("If-Match", tt.header)
+			w := httptest.NewRecorder()
+
+			_, ok := requireVersion(w, r)
+			if ok != tt.ok || w.Code != tt.status {
+				t.Errorf("requireVersion: got = %d, %t, want = %d, %t", w.Code, ok, tt.status, tt.ok)
+			}
+		})
+	}
+}
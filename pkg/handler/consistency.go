@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"cqrs-sample/pkg/query"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const ConsistencyTokenHeader = "X-Consistency-Token"
+
+type (
+	WaitForProjectionQuery interface {
+		Execute(ctx context.Context, token query.ConsistencyToken, timeout time.Duration) error
+	}
+)
+
+// ReadYourWrites holds requests carrying a consistency token until the read
+// model has applied the write it names. When the projection is still behind
+// after timeout the client is told to retry instead of reading stale data.
+func ReadYourWrites(q WaitForProjectionQuery, timeout time.Duration, retryAfter time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			value := r.Header.Get(ConsistencyTokenHeader)
+			if value == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, err := query.ParseConsistencyToken(value)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			err = q.Execute(r.Context(), token, timeout)
+			if errors.Is(err, query.NotCaughtUpErr) {
+				seconds := max(int(retryAfter.Round(time.Second).Seconds()), 1)
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				http.Error(w, err.Error(), http.StatusAccepted)
+				return
+			}
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(query.WithFreshRead(r.Context())))
+		})
+	}
+}
+
+func setConsistencyToken(w http.ResponseWriter, kind query.TokenKind, id string, version int) {
+	w.Header().Set(ConsistencyTokenHeader, query.NewConsistencyToken(kind, id, version).String())
+}
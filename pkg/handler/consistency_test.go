@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"cqrs-sample/pkg/query"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeWaitForProjection struct {
+	err    error
+	tokens []query.ConsistencyToken
+}
+
+func (f *fakeWaitForProjection) Execute(_ context.Context, token query.ConsistencyToken, _ time.Duration) error {
+	f.tokens = append(f.tokens, token)
+	return f.err
+}
+
+func serveReadYourWrites(q WaitForProjectionQuery, token string) (*httptest.ResponseRecorder, bool, bool) {
+	served, fresh := false, false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served, fresh = true, query.IsFreshRead(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/album/1", nil)
+	if token != "" {
+		r.Header.Set(ConsistencyTokenHeader, token)
+	}
+
+	w := httptest.NewRecorder()
+	ReadYourWrites(q, time.Second, 2*time.Second)(next).ServeHTTP(w, r)
+	return w, served, fresh
+}
+
+func Test_Read_Your_Writes_Without_Token_Passes_Through(t *testing.T) {
+	// Arrange
+	q := &fakeWaitForProjection{}
+
+	// Act
+	w, served, fresh := serveReadYourWrites(q, "")
+
+	// Assert
+	if w.Code != http.StatusOK || !served || fresh {
+		t.Errorf("got status = %d, served = %t, fresh = %t", w.Code, served, fresh)
+	}
+
+	if len(q.tokens) != 0 {
+		t.Errorf("waited for %v", q.tokens)
+	}
+}
+
+func Test_Read_Your_Writes_Rejects_Invalid_Token(t *testing.T) {
+	// Act
+	w, served, _ := serveReadYourWrites(&fakeWaitForProjection{}, "album:1")
+
+	// Assert
+	if w.Code != http.StatusBadRequest || served {
+		t.Errorf("got status = %d, served = %t", w.Code, served)
+	}
+}
+
+func Test_Read_Your_Writes_Asks_To_Retry_When_Not_Caught_Up(t *testing.T) {
+	// Act
+	w, served, _ := serveReadYourWrites(&fakeWaitForProjection{err: query.NotCaughtUpErr}, "album:1:3")
+
+	// Assert
+	if w.Code != http.StatusAccepted || served {
+		t.Errorf("got status = %d, served = %t", w.Code, served)
+	}
+
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After: got = %q, want = %q", got, "2")
+	}
+}
+
+func Test_Read_Your_Writes_Reads_Fresh_Once_Caught_Up(t *testing.T) {
+	// Arrange
+	q := &fakeWaitForProjection{}
+
+	// Act
+	w, served, fresh := serveReadYourWrites(q, "album:1:3")
+
+	// Assert
+	if w.Code != http.StatusOK || !served || !fresh {
+		t.Errorf("got status = %d, served = %t, fresh = %t", w.Code, served, fresh)
+	}
+
+	want := query.NewConsistencyToken(query.AlbumToken, "1", 3)
+	if len(q.tokens) != 1 || q.tokens[0] != want {
+		t.Errorf("tokens: got = %v, want = [%v]", q.tokens, want)
+	}
+}
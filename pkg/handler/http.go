@@ -7,19 +7,30 @@ import (
 	"cqrs-sample/pkg/query"
 	"cqrs-sample/pkg/song"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
+	"strconv"
+	"sync"
 )
 
 type (
 	GetArtistQuery interface {
-		Execute(ctx context.Context, id string) (query.ArtistResponse, error)
+		Execute(ctx context.Context, id string) (query.ArtistDetailsResponse, error)
 	}
 
 	SubscribeArtistCommand interface {
 		Execute(ctx context.Context, artist command.SubscribeArtistCommand) (song.Artist, error)
 	}
 
+	MergeArtistsCommand interface {
+		Execute(ctx context.Context, cmd command.MergeArtistsCommand) (song.ArtistMerge, error)
+	}
+
 	GetAlbumQuery interface {
 		Execute(ctx context.Context, id string) (query.AlbumResponse, error)
 	}
@@ -28,8 +39,16 @@ type (
 		Execute(ctx context.Context, artistID string) ([]query.AlbumResponse, error)
 	}
 
-	PublishAlbumCommand interface {
-		Execute(ctx context.Context, cmd command.PublishAlbumCommand) (song.Album, error)
+	GetDuplicateArtistsQuery interface {
+		Execute(ctx context.Context, threshold float64) ([]query.DuplicateArtistsResponse, error)
+	}
+
+	CreateAlbumCommand interface {
+		Execute(ctx context.Context, cmd command.CreateAlbumCommand) (song.Album, error)
+	}
+
+	ScheduleAlbumCommand interface {
+		Execute(ctx context.Context, cmd command.ScheduleAlbumCommand) (song.Album, error)
 	}
 
 	GetSongQuery interface {
@@ -41,16 +60,99 @@ type (
 	}
 
 	PlaySongCommand interface {
-		Execute(ctx context.Context, songID string) error
+		Execute(ctx context.Context, cmd command.PlaySongCommand) error
+	}
+
+	RegisterListenerCommand interface {
+		Execute(ctx context.Context, cmd command.RegisterListenerCommand) (song.Listener, error)
+	}
+
+	FollowArtistCommand interface {
+		Execute(ctx context.Context, cmd command.FollowArtistCommand) (song.Follow, error)
+	}
+
+	LikeSongCommand interface {
+		Execute(ctx context.Context, cmd command.LikeSongCommand) (song.Like, error)
+	}
+
+	RateAlbumCommand interface {
+		Execute(ctx context.Context, cmd command.RateAlbumCommand) (song.AlbumRating, error)
+	}
+
+	GetListeningHistoryQuery interface {
+		Execute(ctx context.Context, listenerID string, p query.Pagination) (query.PageResponse[query.ListeningResponse], error)
+	}
+
+	GetFeedQuery interface {
+		Execute(ctx context.Context, listenerID string, p query.Pagination) (query.PageResponse[query.ReleaseResponse], error)
+	}
+
+	GetLikesQuery interface {
+		Execute(ctx context.Context, listenerID string, p query.Pagination) (query.PageResponse[query.LikeResponse], error)
+	}
+
+	GetAlbumsByGenreQuery interface {
+		Execute(ctx context.Context, genre string, p query.Pagination) (query.PageResponse[query.AlbumResponse], error)
+	}
+
+	GetArtistsByGenreQuery interface {
+		Execute(ctx context.Context, genre string, p query.Pagination) (query.PageResponse[query.ArtistResponse], error)
+	}
+
+	GetPlaylistQuery interface {
+		Execute(ctx context.Context, id string) (query.PlaylistResponse, error)
+	}
+
+	CreatePlaylistCommand interface {
+		Execute(ctx context.Context, cmd command.CreatePlaylistCommand) (song.Playlist, error)
+	}
+
+	RenamePlaylistCommand interface {
+		Execute(ctx context.Context, cmd command.RenamePlaylistCommand) (song.Playlist, error)
+	}
+
+	AddSongToPlaylistCommand interface {
+		Execute(ctx context.Context, cmd command.AddSongToPlaylistCommand) (song.Playlist, error)
+	}
+
+	RemoveSongFromPlaylistCommand interface {
+		Execute(ctx context.Context, cmd command.RemoveSongFromPlaylistCommand) (song.Playlist, error)
+	}
+
+	ReorderPlaylistCommand interface {
+		Execute(ctx context.Context, cmd command.ReorderPlaylistCommand) (song.Playlist, error)
+	}
+
+	ExportCatalogQuery interface {
+		Execute(ctx context.Context, format string, w io.Writer) error
+	}
+
+	GetProjectionsQuery interface {
+		Execute(ctx context.Context) ([]query.ProjectionResponse, error)
+	}
+
+	CreateImportCommand interface {
+		Execute(ctx context.Context, cmd command.CreateImportCommand) (song.ImportJob, error)
+	}
+
+	RunImportCommand interface {
+		Claim(ctx context.Context, id string) (song.ImportJob, error)
+		Run(ctx context.Context, job song.ImportJob) (song.ImportJob, error)
+	}
+
+	GetImportCommand interface {
+		Execute(ctx context.Context, id string) (song.ImportJob, error)
 	}
 
 	ArtistReader struct {
-		artistQuery GetArtistQuery
-		albumsQuery GetAlbumsByArtistQuery
+		artistQuery     GetArtistQuery
+		albumsQuery     GetAlbumsByArtistQuery
+		duplicatesQuery GetDuplicateArtistsQuery
 	}
 
 	ArtistWriter struct {
-		cmd SubscribeArtistCommand
+		subscribeCmd SubscribeArtistCommand
+		mergeCmd     MergeArtistsCommand
 	}
 
 	AlbumReader struct {
@@ -58,7 +160,8 @@ type (
 	}
 
 	AlbumWriter struct {
-		cmd PublishAlbumCommand
+		createCmd   CreateAlbumCommand
+		scheduleCmd ScheduleAlbumCommand
 	}
 
 	SongReader struct {
@@ -69,18 +172,71 @@ type (
 		publishCmd PublishSongCommand
 		playCmd    PlaySongCommand
 	}
+
+	ListenerReader struct {
+		historyQuery GetListeningHistoryQuery
+		feedQuery    GetFeedQuery
+		likesQuery   GetLikesQuery
+	}
+
+	ListenerWriter struct {
+		registerCmd RegisterListenerCommand
+		followCmd   FollowArtistCommand
+		likeCmd     LikeSongCommand
+		rateCmd     RateAlbumCommand
+	}
+
+	GenreReader struct {
+		albumsQuery  GetAlbumsByGenreQuery
+		artistsQuery GetArtistsByGenreQuery
+	}
+
+	PlaylistReader struct {
+		q GetPlaylistQuery
+	}
+
+	ExportReader struct {
+		q ExportCatalogQuery
+	}
+
+	ProjectionReader struct {
+		q GetProjectionsQuery
+	}
+
+	ImportWriter struct {
+		createCmd CreateImportCommand
+		runCmd    RunImportCommand
+		getCmd    GetImportCommand
+		stopRuns  context.Context
+		stop      context.CancelFunc
+		runs      *sync.WaitGroup
+	}
+
+	PlaylistWriter struct {
+		createCmd     CreatePlaylistCommand
+		renameCmd     RenamePlaylistCommand
+		addSongCmd    AddSongToPlaylistCommand
+		removeSongCmd RemoveSongFromPlaylistCommand
+		reorderCmd    ReorderPlaylistCommand
+	}
 )
 
-func NewArtistReader(artistQuery GetArtistQuery, albumsQuery GetAlbumsByArtistQuery) *ArtistReader {
+func NewArtistReader(
+	artistQuery GetArtistQuery,
+	albumsQuery GetAlbumsByArtistQuery,
+	duplicatesQuery GetDuplicateArtistsQuery,
+) *ArtistReader {
 	return &ArtistReader{
-		artistQuery: artistQuery,
-		albumsQuery: albumsQuery,
+		artistQuery:     artistQuery,
+		albumsQuery:     albumsQuery,
+		duplicatesQuery: duplicatesQuery,
 	}
 }
 
-func NewArtistWriter(cmd SubscribeArtistCommand) *ArtistWriter {
+func NewArtistWriter(subscribeCmd SubscribeArtistCommand, mergeCmd MergeArtistsCommand) *ArtistWriter {
 	return &ArtistWriter{
-		cmd: cmd,
+		subscribeCmd: subscribeCmd,
+		mergeCmd:     mergeCmd,
 	}
 }
 
@@ -90,9 +246,10 @@ func NewAlbumReader(albumQuery GetAlbumQuery) *AlbumReader {
 	}
 }
 
-func NewAlbumWriter(cmd PublishAlbumCommand) *AlbumWriter {
+func NewAlbumWriter(createCmd CreateAlbumCommand, scheduleCmd ScheduleAlbumCommand) *AlbumWriter {
 	return &AlbumWriter{
-		cmd: cmd,
+		createCmd:   createCmd,
+		scheduleCmd: scheduleCmd,
 	}
 }
 
@@ -109,15 +266,110 @@ func NewSongWriter(publishCmd PublishSongCommand, playCmd PlaySongCommand) *Song
 	}
 }
 
+func NewListenerReader(
+	historyQuery GetListeningHistoryQuery,
+	feedQuery GetFeedQuery,
+	likesQuery GetLikesQuery,
+) *ListenerReader {
+	return &ListenerReader{
+		historyQuery: historyQuery,
+		feedQuery:    feedQuery,
+		likesQuery:   likesQuery,
+	}
+}
+
+func NewListenerWriter(
+	registerCmd RegisterListenerCommand,
+	followCmd FollowArtistCommand,
+	likeCmd LikeSongCommand,
+	rateCmd RateAlbumCommand,
+) *ListenerWriter {
+	return &ListenerWriter{
+		registerCmd: registerCmd,
+		followCmd:   followCmd,
+		likeCmd:     likeCmd,
+		rateCmd:     rateCmd,
+	}
+}
+
+func NewGenreReader(albumsQuery GetAlbumsByGenreQuery, artistsQuery GetArtistsByGenreQuery) *GenreReader {
+	return &GenreReader{
+		albumsQuery:  albumsQuery,
+		artistsQuery: artistsQuery,
+	}
+}
+
+func NewPlaylistReader(q GetPlaylistQuery) *PlaylistReader {
+	return &PlaylistReader{
+		q: q,
+	}
+}
+
+func NewPlaylistWriter(
+	createCmd CreatePlaylistCommand,
+	renameCmd RenamePlaylistCommand,
+	addSongCmd AddSongToPlaylistCommand,
+	removeSongCmd RemoveSongFromPlaylistCommand,
+	reorderCmd ReorderPlaylistCommand,
+) *PlaylistWriter {
+	return &PlaylistWriter{
+		createCmd:     createCmd,
+		renameCmd:     renameCmd,
+		addSongCmd:    addSongCmd,
+		removeSongCmd: removeSongCmd,
+		reorderCmd:    reorderCmd,
+	}
+}
+
+func NewExportReader(q ExportCatalogQuery) *ExportReader {
+	return &ExportReader{
+		q: q,
+	}
+}
+
+func NewProjectionReader(q GetProjectionsQuery) *ProjectionReader {
+	return &ProjectionReader{
+		q: q,
+	}
+}
+
+func NewImportWriter(createCmd CreateImportCommand, runCmd RunImportCommand, getCmd GetImportCommand) *ImportWriter {
+	stopRuns, stop := context.WithCancel(context.Background())
+	return &ImportWriter{
+		createCmd: createCmd,
+		runCmd:    runCmd,
+		getCmd:    getCmd,
+		stopRuns:  stopRuns,
+		stop:      stop,
+		runs:      &sync.WaitGroup{},
+	}
+}
+
 func (ar ArtistReader) Get(w http.ResponseWriter, r *http.Request) {
 	artistID := chi.URLParam(r, "artistID")
 	artist, err := ar.artistQuery.Execute(r.Context(), artistID)
+	var moved query.ArtistMovedErr
+	if errors.As(err, &moved) {
+		http.Redirect(w, r, "/artist/"+moved.ArtistID, http.StatusMovedPermanently)
+		return
+	}
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		writeQueryError(w, err)
+		return
+	}
+
+	writeConditionalResponse(w, r, artist, artist.Version, artist.UpdatedAt)
+}
+
+func (ar ArtistReader) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	threshold, _ := strconv.ParseFloat(r.URL.Query().Get("threshold"), 64)
+	duplicates, err := ar.duplicatesQuery.Execute(r.Context(), threshold)
+	if err != nil {
+		writeQueryError(w, err)
 		return
 	}
 
-	writeJsonResponse(w, artist, http.StatusOK)
+	writeJsonResponse(w, duplicates, http.StatusOK)
 }
 
 func (ar ArtistReader) GetAlbums(w http.ResponseWriter, r *http.Request) {
@@ -139,53 +391,106 @@ func (aw ArtistWriter) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	artist, err := aw.cmd.Execute(r.Context(), request.ToCommand())
+	artist, err := aw.subscribeCmd.Execute(r.Context(), request.ToCommand())
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		writeCommandError(w, err)
 		return
 	}
 
 	response := presenter.NewSubscribeArtistResponseFromDomain(artist)
+	w.Header().Set("ETag", etag(artist.Version))
+	setConsistencyToken(w, query.ArtistToken, artist.ID, artist.Version)
 	writeJsonResponse(w, response, http.StatusCreated)
 }
 
+func (aw ArtistWriter) Merge(w http.ResponseWriter, r *http.Request) {
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var request presenter.MergeArtistsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	merge, err := aw.mergeCmd.Execute(r.Context(), request.ToCommand(version))
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+
+	response := presenter.NewMergeArtistsResponseFromDomain(merge)
+	w.Header().Set("ETag", etag(merge.Target.Version))
+	setConsistencyToken(w, query.ArtistToken, merge.Target.ID, merge.Target.Version)
+	writeJsonResponse(w, response, http.StatusOK)
+}
+
 func (ar AlbumReader) Get(w http.ResponseWriter, r *http.Request) {
 	albumID := chi.URLParam(r, "albumID")
 	album, err := ar.q.Execute(r.Context(), albumID)
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		writeQueryError(w, err)
 		return
 	}
 
-	writeJsonResponse(w, album, http.StatusOK)
+	writeConditionalResponse(w, r, album, album.Version, album.UpdatedAt)
 }
 
 func (aw AlbumWriter) Create(w http.ResponseWriter, r *http.Request) {
-	var request presenter.PublishAlbumRequest
+	var request presenter.CreateAlbumRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
 
-	album, err := aw.cmd.Execute(r.Context(), request.ToCommand())
+	album, err := aw.createCmd.Execute(r.Context(), request.ToCommand())
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		writeCommandError(w, err)
 		return
 	}
 
-	response := presenter.NewPublishAlbumResponseFromDomain(album)
+	response := presenter.NewCreateAlbumResponseFromDomain(album)
+	w.Header().Set("ETag", etag(album.Version))
+	setConsistencyToken(w, query.AlbumToken, album.ID, album.Version)
 	writeJsonResponse(w, response, http.StatusCreated)
 }
 
+func (aw AlbumWriter) Schedule(w http.ResponseWriter, r *http.Request) {
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var request presenter.ScheduleAlbumRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	albumID := chi.URLParam(r, "albumID")
+	album, err := aw.scheduleCmd.Execute(r.Context(), request.ToCommand(albumID, version))
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+
+	response := presenter.NewCreateAlbumResponseFromDomain(album)
+	w.Header().Set("ETag", etag(album.Version))
+	setConsistencyToken(w, query.AlbumToken, album.ID, album.Version)
+	writeJsonResponse(w, response, http.StatusOK)
+}
+
 func (sr SongReader) Get(w http.ResponseWriter, r *http.Request) {
 	songID := chi.URLParam(r, "songID")
 	s, err := sr.q.Execute(r.Context(), songID)
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		writeQueryError(w, err)
 		return
 	}
 
-	writeJsonResponse(w, s, http.StatusOK)
+	writeConditionalResponse(w, r, s, s.Version, s.UpdatedAt)
 }
 
 func (sw SongWriter) Create(w http.ResponseWriter, r *http.Request) {
@@ -197,11 +502,13 @@ func (sw SongWriter) Create(w http.ResponseWriter, r *http.Request) {
 
 	s, err := sw.publishCmd.Execute(r.Context(), request.ToCommand())
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		writeCommandError(w, err)
 		return
 	}
 
 	response := presenter.NewPublishSongResponseFromDomain(s)
+	w.Header().Set("ETag", etag(s.Version))
+	setConsistencyToken(w, query.SongToken, s.ID, s.Version)
 	writeJsonResponse(w, response, http.StatusCreated)
 }
 
@@ -212,13 +519,441 @@ func (sw SongWriter) Play(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := sw.playCmd.Execute(r.Context(), request.SongID); err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	if err := sw.playCmd.Execute(r.Context(), request.ToCommand()); err != nil {
+		writeCommandError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (lr ListenerReader) History(w http.ResponseWriter, r *http.Request) {
+	listenerID := chi.URLParam(r, "listenerID")
+	history, err := lr.historyQuery.Execute(r.Context(), listenerID, parsePagination(r))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	writeJsonResponse(w, history, http.StatusOK)
+}
+
+func (lw ListenerWriter) Create(w http.ResponseWriter, r *http.Request) {
+	var request presenter.RegisterListenerRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	listener, err := lw.registerCmd.Execute(r.Context(), request.ToCommand())
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+
+	response := presenter.NewRegisterListenerResponseFromDomain(listener)
+	writeJsonResponse(w, response, http.StatusCreated)
+}
+
+func (lr ListenerReader) Feed(w http.ResponseWriter, r *http.Request) {
+	listenerID := chi.URLParam(r, "listenerID")
+	feed, err := lr.feedQuery.Execute(r.Context(), listenerID, parsePagination(r))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	writeJsonResponse(w, feed, http.StatusOK)
+}
+
+func (lw ListenerWriter) Follow(w http.ResponseWriter, r *http.Request) {
+	var request presenter.FollowArtistRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	listenerID := chi.URLParam(r, "listenerID")
+	follow, err := lw.followCmd.Execute(r.Context(), request.ToCommand(listenerID))
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+
+	response := presenter.NewFollowArtistResponseFromDomain(follow)
+	writeJsonResponse(w, response, http.StatusCreated)
+}
+
+func (lr ListenerReader) Likes(w http.ResponseWriter, r *http.Request) {
+	listenerID := chi.URLParam(r, "listenerID")
+	likes, err := lr.likesQuery.Execute(r.Context(), listenerID, parsePagination(r))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	writeJsonResponse(w, likes, http.StatusOK)
+}
+
+func (lw ListenerWriter) Like(w http.ResponseWriter, r *http.Request) {
+	var request presenter.LikeSongRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	listenerID := chi.URLParam(r, "listenerID")
+	like, err := lw.likeCmd.Execute(r.Context(), request.ToCommand(listenerID))
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+
+	response := presenter.NewLikeSongResponseFromDomain(like)
+	writeJsonResponse(w, response, http.StatusCreated)
+}
+
+func (lw ListenerWriter) Rate(w http.ResponseWriter, r *http.Request) {
+	var request presenter.RateAlbumRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	listenerID := chi.URLParam(r, "listenerID")
+	rating, err := lw.rateCmd.Execute(r.Context(), request.ToCommand(listenerID))
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+
+	response := presenter.NewRateAlbumResponseFromDomain(rating)
+	writeJsonResponse(w, response, http.StatusCreated)
+}
+
+func (gr GenreReader) GetAlbums(w http.ResponseWriter, r *http.Request) {
+	genre := chi.URLParam(r, "genre")
+	albums, err := gr.albumsQuery.Execute(r.Context(), genre, parsePagination(r))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	writeJsonResponse(w, albums, http.StatusOK)
+}
+
+func (gr GenreReader) GetArtists(w http.ResponseWriter, r *http.Request) {
+	genre := chi.URLParam(r, "genre")
+	artists, err := gr.artistsQuery.Execute(r.Context(), genre, parsePagination(r))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	writeJsonResponse(w, artists, http.StatusOK)
+}
+
+func (pr PlaylistReader) Get(w http.ResponseWriter, r *http.Request) {
+	playlistID := chi.URLParam(r, "playlistID")
+	playlist, err := pr.q.Execute(r.Context(), playlistID)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	writeJsonResponse(w, playlist, http.StatusOK)
+}
+
+func (pw PlaylistWriter) Create(w http.ResponseWriter, r *http.Request) {
+	var request presenter.CreatePlaylistRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	playlist, err := pw.createCmd.Execute(r.Context(), request.ToCommand())
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+
+	response := presenter.NewPlaylistResponseFromDomain(playlist)
+	w.Header().Set("ETag", etag(playlist.Version))
+	setConsistencyToken(w, query.PlaylistToken, playlist.ID, playlist.Version)
+	writeJsonResponse(w, response, http.StatusCreated)
+}
+
+func (pw PlaylistWriter) Rename(w http.ResponseWriter, r *http.Request) {
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var request presenter.RenamePlaylistRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	playlistID := chi.URLParam(r, "playlistID")
+	playlist, err := pw.renameCmd.Execute(r.Context(), request.ToCommand(playlistID, version))
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+
+	response := presenter.NewPlaylistResponseFromDomain(playlist)
+	w.Header().Set("ETag", etag(playlist.Version))
+	setConsistencyToken(w, query.PlaylistToken, playlist.ID, playlist.Version)
+	writeJsonResponse(w, response, http.StatusOK)
+}
+
+func (pw PlaylistWriter) AddSong(w http.ResponseWriter, r *http.Request) {
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var request presenter.AddSongToPlaylistRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	playlistID := chi.URLParam(r, "playlistID")
+	playlist, err := pw.addSongCmd.Execute(r.Context(), request.ToCommand(playlistID, version))
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+
+	response := presenter.NewPlaylistResponseFromDomain(playlist)
+	w.Header().Set("ETag", etag(playlist.Version))
+	setConsistencyToken(w, query.PlaylistToken, playlist.ID, playlist.Version)
+	writeJsonResponse(w, response, http.StatusOK)
+}
+
+func (pw PlaylistWriter) RemoveSong(w http.ResponseWriter, r *http.Request) {
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	playlist, err := pw.removeSongCmd.Execute(r.Context(), command.RemoveSongFromPlaylistCommand{
+		PlaylistID: chi.URLParam(r, "playlistID"),
+		SongID:     chi.URLParam(r, "songID"),
+		Version:    version,
+	})
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+
+	response := presenter.NewPlaylistResponseFromDomain(playlist)
+	w.Header().Set("ETag", etag(playlist.Version))
+	setConsistencyToken(w, query.PlaylistToken, playlist.ID, playlist.Version)
+	writeJsonResponse(w, response, http.StatusOK)
+}
+
+func (pw PlaylistWriter) Reorder(w http.ResponseWriter, r *http.Request) {
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var request presenter.ReorderPlaylistRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	playlistID := chi.URLParam(r, "playlistID")
+	playlist, err := pw.reorderCmd.Execute(r.Context(), request.ToCommand(playlistID, version))
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+
+	response := presenter.NewPlaylistResponseFromDomain(playlist)
+	w.Header().Set("ETag", etag(playlist.Version))
+	setConsistencyToken(w, query.PlaylistToken, playlist.ID, playlist.Version)
+	writeJsonResponse(w, response, http.StatusOK)
+}
+
+func (er ExportReader) Catalog(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = query.JSONLFormat
+	}
+
+	if err := query.ValidateExportFormat(format); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contentType := "application/x-ndjson"
+	if format == query.CSVFormat {
+		contentType = "text/csv"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="catalog.%s"`, format))
+	w.WriteHeader(http.StatusOK)
+
+	// The status line is already out once streaming starts, so a failure
+	// midway can only cut the body short.
+	if err := er.q.Execute(r.Context(), format, w); err != nil {
+		slog.ErrorContext(r.Context(), "catalog export failed", "format", format, "error", err)
+	}
+}
+
+func (pr ProjectionReader) List(w http.ResponseWriter, r *http.Request) {
+	projections, err := pr.q.Execute(r.Context())
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	writeJsonResponse(w, projections, http.StatusOK)
+}
+
+func (iw ImportWriter) Create(w http.ResponseWriter, r *http.Request) {
+	format := importFormat(r)
+	job, err := iw.createCmd.Execute(r.Context(), command.CreateImportCommand{
+		Format: format,
+		Data:   r.Body,
+	})
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+
+	iw.claimAndRun(w, r, job.ID)
+}
+
+func (iw ImportWriter) Get(w http.ResponseWriter, r *http.Request) {
+	importID := chi.URLParam(r, "importID")
+	job, err := iw.getCmd.Execute(r.Context(), importID)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	writeJsonResponse(w, presenter.NewImportJobResponseFromDomain(job), http.StatusOK)
+}
+
+func (iw ImportWriter) Resume(w http.ResponseWriter, r *http.Request) {
+	iw.claimAndRun(w, r, chi.URLParam(r, "importID"))
+}
+
+// Shutdown stops the imports running in the background, which release their
+// jobs back to pending, and waits for them until ctx is done.
+func (iw ImportWriter) Shutdown(ctx context.Context) error {
+	iw.stop()
+
+	done := make(chan struct{})
+	go func() {
+		iw.runs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// claimAndRun claims the job before answering, so a job already running is
+// a 409, then processes it in the background, detached from the request so
+// the import outlives it until Shutdown; progress is followed through the
+// job status.
+func (iw ImportWriter) claimAndRun(w http.ResponseWriter, r *http.Request, id string) {
+	job, err := iw.runCmd.Claim(r.Context(), id)
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+
+	if job.Status != song.ImportCompleted {
+		ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+		stop := context.AfterFunc(iw.stopRuns, cancel)
+		iw.runs.Add(1)
+		go func() {
+			defer iw.runs.Done()
+			defer stop()
+			defer cancel()
+
+			if _, err := iw.runCmd.Run(ctx, job); err != nil {
+				slog.ErrorContext(ctx, "import failed", "import_id", job.ID, "error", err)
+			}
+		}()
+	}
+
+	w.Header().Set("Location", "/imports/"+job.ID)
+	writeJsonResponse(w, presenter.NewImportJobResponseFromDomain(job), http.StatusAccepted)
+}
+
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return command.CSVFormat
+	case "application/jsonl", "application/x-ndjson":
+		return command.JSONLFormat
+	default:
+		return mediaType
+	}
+}
+
+func writeCommandError(w http.ResponseWriter, err error) {
+	if errors.Is(err, command.InvalidCommandErr) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if errors.Is(err, song.NotFoundErr) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	if errors.Is(err, song.VersionConflictErr) {
+		http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+		return
+	}
+
+	if errors.Is(err, command.ImportRunningErr) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	if errors.Is(err, song.AlreadyExistsErr) {
+		http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
+		return
+	}
+
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func writeQueryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, song.NotFoundErr) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func parsePagination(r *http.Request) query.Pagination {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+	return query.NewPagination(page, size)
+}
+
 func writeJsonResponse(w http.ResponseWriter, output any, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
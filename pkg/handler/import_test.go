@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"cqrs-sample/pkg/command"
+	"cqrs-sample/pkg/song"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeRunImport struct {
+	claimErr       error
+	runs           chan string
+	untilCancelled bool
+}
+
+func (f fakeRunImport) Claim(_ context.Context, id string) (song.ImportJob, error) {
+	if f.claimErr != nil {
+		return song.ImportJob{}, f.claimErr
+	}
+
+	return song.ImportJob{ID: id, Status: song.ImportRunning}, nil
+}
+
+func (f fakeRunImport) Run(ctx context.Context, job song.ImportJob) (song.ImportJob, error) {
+	if f.untilCancelled {
+		<-ctx.Done()
+	}
+
+	f.runs <- job.ID
+	return job, ctx.Err()
+}
+
+func serveResume(runCmd RunImportCommand) *httptest.ResponseRecorder {
+	return serveResumeWith(NewImportWriter(nil, runCmd, nil))
+}
+
+func serveResumeWith(iw *ImportWriter) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.Post("/imports/{importID}/resume", iw.Resume)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/imports/1/resume", nil))
+	return w
+}
+
+func Test_Import_Resume_Runs_Claimed_Job(t *testing.T) {
+	// Arrange
+	runCmd := fakeRunImport{runs: make(chan string, 1)}
+
+	// Act
+	w := serveResume(runCmd)
+
+	// Assert
+	if w.Code != http.StatusAccepted {
+		t.Errorf("status: got = %d, want = %d", w.Code, http.StatusAccepted)
+	}
+
+	if id := <-runCmd.runs; id != "1" {
+		t.Errorf("run: got = %s, want = 1", id)
+	}
+}
+
+func Test_Import_Resume_Conflicts_With_Running_Job(t *testing.T) {
+	// Arrange
+	runCmd := fakeRunImport{claimErr: command.ImportRunningErr, runs: make(chan string, 1)}
+
+	// Act
+	w := serveResume(runCmd)
+
+	// Assert
+	if w.Code != http.StatusConflict {
+		t.Errorf("status: got = %d, want = %d", w.Code, http.StatusConflict)
+	}
+
+	if len(runCmd.runs) != 0 {
+		t.Errorf("run: got = %d, want = 0", len(runCmd.runs))
+	}
+}
+
+func Test_Import_Shutdown_Stops_Running_Jobs(t *testing.T) {
+	// Arrange
+	runCmd := fakeRunImport{runs: make(chan string, 1), untilCancelled: true}
+	iw := NewImportWriter(nil, runCmd, nil)
+	serveResumeWith(iw)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Act
+	err := iw.Shutdown(ctx)
+
+	// Assert
+	if err != nil {
+		t.Errorf("shutdown: got = %v, want = nil", err)
+	}
+
+	if len(runCmd.runs) != 1 {
+		t.Errorf("stopped runs: got = %d, want = 1", len(runCmd.runs))
+	}
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"cqrs-sample/pkg/song"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var (
+	MissingDependencyErr = errors.New("missing dependency")
+)
+
+type (
+	// DependencyErr is returned by handlers that received an event before
+	// the resource it refers to was projected.
+	DependencyErr struct {
+		Dependency string
+	}
+
+	// Provider is implemented by handlers whose events project resources
+	// other events may depend on.
+	Provider interface {
+		Provides(body []byte) []string
+	}
+
+	ParkingDatabase interface {
+		ParkEvent(ctx context.Context, parked song.ParkedEvent) error
+		GetParkedEvents(ctx context.Context, dependency string) ([]song.ParkedEvent, error)
+		DeleteParkedEvent(ctx context.Context, id string) (bool, error)
+	}
+
+	// Parking lets projections tolerate events arriving out of order across
+	// queues: events failing with DependencyErr are parked and acknowledged,
+	// then replayed as soon as a Provider projects what they were waiting for.
+	Parking struct {
+		db       ParkingDatabase
+		handlers map[string]QueueHandler
+	}
+
+	parked struct {
+		parking *Parking
+		name    string
+		next    QueueHandler
+	}
+)
+
+func (e DependencyErr) Error() string {
+	return fmt.Sprintf("%s: %s", MissingDependencyErr, e.Dependency)
+}
+
+func (e DependencyErr) Unwrap() error {
+	return MissingDependencyErr
+}
+
+func NewParking(db ParkingDatabase) *Parking {
+	return &Parking{
+		db:       db,
+		handlers: make(map[string]QueueHandler),
+	}
+}
+
+// Wrap registers next under name, which must be unique and stable across
+// restarts since parked events are replayed by it.
+func (p *Parking) Wrap(name string, next QueueHandler) QueueHandler {
+	p.handlers[name] = next
+	return parked{
+		parking: p,
+		name:    name,
+		next:    next,
+	}
+}
+
+func (ph parked) Handle(ctx context.Context, body []byte, headers map[string]interface{}) error {
+	err := ph.parking.apply(ctx, ph.next, body, headers)
+	var dependency DependencyErr
+	if !errors.As(err, &dependency) {
+		return err
+	}
+
+	sum := sha256.Sum256(append([]byte(ph.name+"\x00"), body...))
+	event := song.ParkedEvent{
+		ID:         hex.EncodeToString(sum[:]),
+		Handler:    ph.name,
+		Dependency: dependency.Dependency,
+		Body:       body,
+		Headers:    headers,
+		ParkedAt:   time.Now().UTC(),
+	}
+	if err := ph.parking.db.ParkEvent(ctx, event); err != nil {
+		return err
+	}
+
+	// the dependency may have been projected between the failed attempt and
+	// parking, in which case nothing else would release the event
+	if err := ph.parking.replay(ctx, event); err != nil {
+		if _, deleteErr := ph.parking.db.DeleteParkedEvent(ctx, event.ID); deleteErr != nil {
+			slog.ErrorContext(ctx, "unpark event failed", "handler", event.Handler, "error", deleteErr)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (p *Parking) apply(ctx context.Context, next QueueHandler, body []byte, headers map[string]interface{}) error {
+	if err := next.Handle(ctx, body, headers); err != nil {
+		return err
+	}
+
+	if provider, ok := next.(Provider); ok {
+		for _, dependency := range provider.Provides(body) {
+			if err := p.release(ctx, dependency); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// release replays the events parked on dependency. Events that failed stay
+// parked and the error is returned, so the provider event is retried.
+func (p *Parking) release(ctx context.Context, dependency string) error {
+	events, err := p.db.GetParkedEvents(ctx, dependency)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, event := range events {
+		if err := p.replay(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("replay %s on %s: %w", event.Handler, dependency, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// replay removes a parked event once it is applied, or parks it on the next
+// dependency it is missing.
+func (p *Parking) replay(ctx context.Context, event song.ParkedEvent) error {
+	next, ok := p.handlers[event.Handler]
+	if !ok {
+		return nil
+	}
+
+	err := p.apply(ctx, next, event.Body, event.Headers)
+	var missing DependencyErr
+	switch {
+	case err == nil:
+		_, err = p.db.DeleteParkedEvent(ctx, event.ID)
+		return err
+	case errors.As(err, &missing):
+		event.Dependency = missing.Dependency
+		return p.db.ParkEvent(ctx, event)
+	default:
+		return err
+	}
+}
+
+func albumDependency(id string) string {
+	return "album:" + id
+}
+
+func songDependency(id string) string {
+	return "song:" + id
+}
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"context"
+	"cqrs-sample/pkg/song"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type (
+	fakeParkingDatabase struct {
+		mu     sync.Mutex
+		events map[string]song.ParkedEvent
+	}
+
+	fakeQueueHandler struct {
+		handle   func(body []byte) error
+		provides []string
+	}
+
+	fakeProvider struct {
+		fakeQueueHandler
+	}
+)
+
+func newFakeParkingDatabase() *fakeParkingDatabase {
+	return &fakeParkingDatabase{
+		events: make(map[string]song.ParkedEvent),
+	}
+}
+
+func (f *fakeParkingDatabase) ParkEvent(_ context.Context, parked song.ParkedEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.events[parked.ID] = parked
+	return nil
+}
+
+func (f *fakeParkingDatabase) GetParkedEvents(_ context.Context, dependency string) ([]song.ParkedEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var output []song.ParkedEvent
+	for _, event := range f.events {
+		if event.Dependency == dependency {
+			output = append(output, event)
+		}
+	}
+
+	return output, nil
+}
+
+func (f *fakeParkingDatabase) DeleteParkedEvent(_ context.Context, id string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, ok := f.events[id]
+	delete(f.events, id)
+	return ok, nil
+}
+
+func (f *fakeParkingDatabase) dependencies() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var output []string
+	for _, event := range f.events {
+		output = append(output, event.Dependency)
+	}
+
+	return output
+}
+
+func (f fakeQueueHandler) Handle(_ context.Context, body []byte, _ map[string]interface{}) error {
+	return f.handle(body)
+}
+
+func (f fakeProvider) Provides(_ []byte) []string {
+	return f.provides
+}
+
+func Test_Parking_Parks_Event_Missing_Dependency(t *testing.T) {
+	// Arrange
+	db := newFakeParkingDatabase()
+	parking := NewParking(db)
+	consumer := parking.Wrap("song.played", fakeQueueHandler{handle: func([]byte) error {
+		return DependencyErr{Dependency: songDependency("1")}
+	}})
+
+	// Act
+	err := consumer.Handle(context.Background(), []byte(`{"song_id":"1"}`), nil)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("err: got = %v, want = nil", err)
+	}
+
+	if got := db.dependencies(); len(got) != 1 || got[0] != "song:1" {
+		t.Errorf("parked: got = %v, want = [song:1]", got)
+	}
+}
+
+func Test_Parking_Releases_Event_When_Dependency_Is_Projected(t *testing.T) {
+	// Arrange
+	db := newFakeParkingDatabase()
+	parking := NewParking(db)
+	projected, applied := false, 0
+	consumer := parking.Wrap("song.played", fakeQueueHandler{handle: func([]byte) error {
+		if !projected {
+			return DependencyErr{Dependency: songDependency("1")}
+		}
+
+		applied++
+		return nil
+	}})
+	provider := parking.Wrap("song.published", fakeProvider{fakeQueueHandler{
+		handle: func([]byte) error {
+			projected = true
+			return nil
+		},
+		provides: []string{songDependency("1")},
+	}})
+	ctx := context.Background()
+
+	// Act
+	if err := consumer.Handle(ctx, []byte(`{"song_id":"1"}`), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	err := provider.Handle(ctx, []byte(`{"id":"1"}`), nil)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("err: got = %v, want = nil", err)
+	}
+
+	if applied != 1 {
+		t.Errorf("applied: got = %d, want = 1", applied)
+	}
+
+	if got := db.dependencies(); len(got) != 0 {
+		t.Errorf("parked: got = %v, want = []", got)
+	}
+}
+
+func Test_Parking_Reparks_Event_On_Next_Missing_Dependency(t *testing.T) {
+	// Arrange
+	db := newFakeParkingDatabase()
+	parking := NewParking(db)
+	songProjected := false
+	consumer := parking.Wrap("song.liked", fakeQueueHandler{handle: func([]byte) error {
+		if !songProjected {
+			return DependencyErr{Dependency: songDependency("1")}
+		}
+
+		return DependencyErr{Dependency: albumDependency("2")}
+	}})
+	provider := parking.Wrap("song.published", fakeProvider{fakeQueueHandler{
+		handle: func([]byte) error {
+			songProjected = true
+			return nil
+		},
+		provides: []string{songDependency("1")},
+	}})
+	ctx := context.Background()
+
+	// Act
+	if err := consumer.Handle(ctx, []byte(`{"song_id":"1"}`), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	err := provider.Handle(ctx, []byte(`{"id":"1"}`), nil)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("err: got = %v, want = nil", err)
+	}
+
+	if got := db.dependencies(); len(got) != 1 || got[0] != "album:2" {
+		t.Errorf("parked: got = %v, want = [album:2]", got)
+	}
+}
+
+func Test_Parking_Keeps_Event_Parked_When_Replay_Fails(t *testing.T) {
+	// Arrange
+	db := newFakeParkingDatabase()
+	parking := NewParking(db)
+	projected, failing := false, true
+	replayErr := errors.New("timeout")
+	consumer := parking.Wrap("song.played", fakeQueueHandler{handle: func([]byte) error {
+		if !projected {
+			return DependencyErr{Dependency: songDependency("1")}
+		}
+
+		if failing {
+			return replayErr
+		}
+
+		return nil
+	}})
+	provider := parking.Wrap("song.published", fakeProvider{fakeQueueHandler{
+		handle: func([]byte) error {
+			projected = true
+			return nil
+		},
+		provides: []string{songDependency("1")},
+	}})
+	ctx := context.Background()
+	if err := consumer.Handle(ctx, []byte(`{"song_id":"1"}`), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	err := provider.Handle(ctx, []byte(`{"id":"1"}`), nil)
+
+	// Assert
+	if !errors.Is(err, replayErr) {
+		t.Fatalf("err: got = %v, want = %v", err, replayErr)
+	}
+
+	if got := db.dependencies(); len(got) != 1 || got[0] != "song:1" {
+		t.Errorf("parked: got = %v, want = [song:1]", got)
+	}
+
+	// Act: the provider event is redelivered
+	failing = false
+	err = provider.Handle(ctx, []byte(`{"id":"1"}`), nil)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("err: got = %v, want = nil", err)
+	}
+
+	if got := db.dependencies(); len(got) != 0 {
+		t.Errorf("parked: got = %v, want = []", got)
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"cqrs-sample/pkg/message"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPlaysWindow    = 250 * time.Millisecond
+	DefaultPlaysBatchSize = 1000
+)
+
+type (
+	PlaysDatabase interface {
+		AddSongPlays(ctx context.Context, plays map[string][]string) ([]string, error)
+	}
+
+	// BatchSongPlays accumulates SONG_PLAYED events for a short window and
+	// counts them with a single write. Handle returns only once the window
+	// holding its event is flushed, so the message is acknowledged after
+	// the play is stored and requeued if the flush fails; plays are written
+	// by id, so the ones a failed flush already counted are not counted
+	// again. A batch only fills up if the consumer hands it size messages
+	// at once, so its queue should run with a concurrency of at least size.
+	BatchSongPlays struct {
+		db     PlaysDatabase
+		window time.Duration
+		size   int
+
+		mu      sync.Mutex
+		pending *playsBatch
+	}
+
+	playsBatch struct {
+		ctx     context.Context
+		plays   map[string][]string
+		events  int
+		once    sync.Once
+		done    chan struct{}
+		missing map[string]bool
+		err     error
+	}
+)
+
+func NewBatchSongPlays(db PlaysDatabase, window time.Duration, size int) *BatchSongPlays {
+	if window <= 0 {
+		window = DefaultPlaysWindow
+	}
+
+	if size <= 0 {
+		size = DefaultPlaysBatchSize
+	}
+
+	return &BatchSongPlays{
+		db:     db,
+		window: window,
+		size:   size,
+	}
+}
+
+func (bp *BatchSongPlays) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
+	ps, err := unmarshal[message.PlaySong](body)
+	if err != nil {
+		return err
+	}
+
+	batch := bp.add(ctx, ps.SongID, ps.ID)
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if batch.err != nil {
+		return batch.err
+	}
+
+	if batch.missing[ps.SongID] {
+		return DependencyErr{Dependency: songDependency(ps.SongID)}
+	}
+
+	return nil
+}
+
+func (bp *BatchSongPlays) add(ctx context.Context, songID, playID string) *playsBatch {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	batch := bp.pending
+	if batch == nil {
+		batch = &playsBatch{
+			ctx:   context.WithoutCancel(ctx),
+			plays: make(map[string][]string),
+			done:  make(chan struct{}),
+		}
+		bp.pending = batch
+		time.AfterFunc(bp.window, func() {
+			bp.flush(batch)
+		})
+	}
+
+	batch.plays[songID] = append(batch.plays[songID], playID)
+	batch.events++
+	if batch.events >= bp.size {
+		go bp.flush(batch)
+	}
+
+	return batch
+}
+
+// flush writes batch once, whether its window elapsed or it filled up.
+func (bp *BatchSongPlays) flush(batch *playsBatch) {
+	batch.once.Do(func() {
+		bp.mu.Lock()
+		if bp.pending == batch {
+			bp.pending = nil
+		}
+		bp.mu.Unlock()
+
+		missing, err := bp.db.AddSongPlays(batch.ctx, batch.plays)
+		batch.err = err
+		batch.missing = make(map[string]bool, len(missing))
+		for _, songID := range missing {
+			batch.missing[songID] = true
+		}
+
+		close(batch.done)
+	})
+}
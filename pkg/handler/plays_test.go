@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakePlaysDatabase struct {
+	mu      sync.Mutex
+	batches []map[string][]string
+	missing []string
+	err     error
+}
+
+func (f *fakePlaysDatabase) AddSongPlays(_ context.Context, plays map[string][]string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.batches = append(f.batches, plays)
+	return f.missing, f.err
+}
+
+func handlePlays(bp *BatchSongPlays, bodies ...string) []error {
+	errs := make([]error, len(bodies))
+	var wg sync.WaitGroup
+	for i, body := range bodies {
+		wg.Add(1)
+		go func(i int, body string) {
+			defer wg.Done()
+			errs[i] = bp.Handle(context.Background(), []byte(body), nil)
+		}(i, body)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+func Test_Batch_Song_Plays_Writes_Play_IDs_Once_Full(t *testing.T) {
+	// Arrange
+	db := &fakePlaysDatabase{}
+	bp := NewBatchSongPlays(db, time.Hour, 3)
+
+	// Act
+	errs := handlePlays(bp,
+		`{"id":"a","song_id":"1"}`,
+		`{"id":"b","song_id":"1"}`,
+		`{"id":"c","song_id":"2"}`,
+	)
+
+	// Assert
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("play %d: got = %v, want = nil", i, err)
+		}
+	}
+
+	if len(db.batches) != 1 {
+		t.Fatalf("batches: got = %d, want = 1", len(db.batches))
+	}
+
+	plays := db.batches[0]
+	if len(plays["1"]) != 2 || !reflect.DeepEqual(plays["2"], []string{"c"}) {
+		t.Errorf("plays: got = %v, want = map[1:[a b] 2:[c]]", plays)
+	}
+}
+
+func Test_Batch_Song_Plays_Flushes_After_Window(t *testing.T) {
+	// Arrange
+	db := &fakePlaysDatabase{}
+	bp := NewBatchSongPlays(db, time.Millisecond, 1000)
+
+	// Act
+	errs := handlePlays(bp, `{"id":"a","song_id":"1"}`)
+
+	// Assert
+	if errs[0] != nil {
+		t.Errorf("err: got = %v, want = nil", errs[0])
+	}
+
+	if len(db.batches) != 1 {
+		t.Errorf("batches: got = %d, want = 1", len(db.batches))
+	}
+}
+
+func Test_Batch_Song_Plays_Parks_Plays_Of_Missing_Songs(t *testing.T) {
+	// Arrange
+	db := &fakePlaysDatabase{missing: []string{"2"}}
+	bp := NewBatchSongPlays(db, time.Hour, 2)
+
+	// Act
+	errs := handlePlays(bp, `{"id":"a","song_id":"1"}`, `{"id":"b","song_id":"2"}`)
+
+	// Assert
+	if errs[0] != nil {
+		t.Errorf("projected song: got = %v, want = nil", errs[0])
+	}
+
+	var dependencyErr DependencyErr
+	if !errors.As(errs[1], &dependencyErr) || dependencyErr.Dependency != songDependency("2") {
+		t.Errorf("missing song: got = %v, want = %v", errs[1], songDependency("2"))
+	}
+}
+
+func Test_Batch_Song_Plays_Fails_Every_Play_Of_Failed_Flush(t *testing.T) {
+	// Arrange
+	flushErr := errors.New("timeout")
+	db := &fakePlaysDatabase{err: flushErr}
+	bp := NewBatchSongPlays(db, time.Hour, 2)
+
+	// Act
+	errs := handlePlays(bp, `{"id":"a","song_id":"1"}`, `{"id":"b","song_id":"2"}`)
+
+	// Assert
+	for i, err := range errs {
+		if !errors.Is(err, flushErr) {
+			t.Errorf("play %d: got = %v, want = %v", i, err, flushErr)
+		}
+	}
+}
@@ -3,76 +3,314 @@ package presenter
 import (
 	"cqrs-sample/pkg/command"
 	"cqrs-sample/pkg/song"
+	"time"
 )
 
 type (
 	SubscribeArtistRequest struct {
-		Name   string `json:"name"`
-		Gender string `json:"gender"`
+		Name   string   `json:"name"`
+		Gender string   `json:"gender"`
+		Genres []string `json:"genres"`
 	}
 
 	SubscribeArtistResponse struct {
-		ID     string `json:"id"`
-		Name   string `json:"name"`
-		Gender string `json:"gender"`
+		ID     string   `json:"id"`
+		Name   string   `json:"name"`
+		Gender string   `json:"gender"`
+		Genres []string `json:"genres,omitempty"`
 	}
 
-	PublishAlbumRequest struct {
-		Title       string `json:"title"`
-		ArtistID    string `json:"artist_id"`
-		ReleaseYear int    `json:"release_year"`
+	MergeArtistsRequest struct {
+		SourceID string `json:"source_id"`
+		TargetID string `json:"target_id"`
+	}
+
+	MergeArtistsResponse struct {
+		SourceID string                  `json:"source_id"`
+		Target   SubscribeArtistResponse `json:"target"`
+		MergedAt time.Time               `json:"merged_at"`
+	}
+
+	CreateAlbumRequest struct {
+		Title       string   `json:"title"`
+		ArtistID    string   `json:"artist_id"`
+		ReleaseYear int      `json:"release_year"`
+		Genres      []string `json:"genres"`
 	}
 
 	AlbumResponse struct {
-		ID          string `json:"id"`
-		Title       string `json:"title"`
-		ReleaseYear int    `json:"release_year"`
+		ID          string     `json:"id"`
+		Title       string     `json:"title"`
+		ReleaseYear int        `json:"release_year"`
+		Status      string     `json:"status,omitempty"`
+		ReleaseDate *time.Time `json:"release_date,omitempty"`
+		Genres      []string   `json:"genres,omitempty"`
+	}
+
+	ImportJobResponse struct {
+		ID        string              `json:"id"`
+		Format    string              `json:"format"`
+		Status    string              `json:"status"`
+		Total     int                 `json:"total"`
+		Pending   int                 `json:"pending"`
+		Succeeded int                 `json:"succeeded"`
+		Failed    int                 `json:"failed"`
+		Rows      []ImportRowResponse `json:"rows"`
+		CreatedAt time.Time           `json:"created_at"`
+		UpdatedAt time.Time           `json:"updated_at"`
+	}
+
+	ImportRowResponse struct {
+		Line       int    `json:"line"`
+		Type       string `json:"type,omitempty"`
+		Key        string `json:"key,omitempty"`
+		Status     string `json:"status"`
+		ResourceID string `json:"resource_id,omitempty"`
+		Error      string `json:"error,omitempty"`
 	}
 
-	PublishAlbumResponse struct {
+	ScheduleAlbumRequest struct {
+		ReleaseDate time.Time `json:"release_date"`
+	}
+
+	CreateAlbumResponse struct {
 		AlbumResponse
 		Artist SubscribeArtistResponse `json:"artist"`
 	}
 
 	PublishSongRequest struct {
-		TrackNumber int    `json:"track_number"`
-		Title       string `json:"title"`
-		AlbumID     string `json:"album_id"`
+		TrackNumber int                     `json:"track_number"`
+		DiscNumber  int                     `json:"disc_number"`
+		Title       string                  `json:"title"`
+		AlbumID     string                  `json:"album_id"`
+		DurationMs  int64                   `json:"duration_ms"`
+		ISRC        string                  `json:"isrc"`
+		Explicit    bool                    `json:"explicit"`
+		Composers   []string                `json:"composers"`
+		Featuring   []FeaturedArtistRequest `json:"featuring"`
+	}
+
+	FeaturedArtistRequest struct {
+		ArtistID string `json:"artist_id"`
+		Role     string `json:"role"`
 	}
 
 	PublishSongResponse struct {
-		ID          string                  `json:"id"`
-		TrackNumber int                     `json:"track_number"`
-		Title       string                  `json:"title"`
-		Album       AlbumResponse           `json:"album"`
-		Artist      SubscribeArtistResponse `json:"artist"`
+		ID          string                   `json:"id"`
+		TrackNumber int                      `json:"track_number"`
+		DiscNumber  int                      `json:"disc_number"`
+		Title       string                   `json:"title"`
+		DurationMs  int64                    `json:"duration_ms"`
+		ISRC        string                   `json:"isrc,omitempty"`
+		Explicit    bool                     `json:"explicit"`
+		Composers   []string                 `json:"composers,omitempty"`
+		Album       AlbumResponse            `json:"album"`
+		Artist      SubscribeArtistResponse  `json:"artist"`
+		Featuring   []FeaturedArtistResponse `json:"featuring,omitempty"`
+	}
+
+	FeaturedArtistResponse struct {
+		Artist SubscribeArtistResponse `json:"artist"`
+		Role   string                  `json:"role"`
 	}
 
 	PlaySongRequest struct {
+		SongID     string `json:"song_id"`
+		ListenerID string `json:"listener_id"`
+	}
+
+	RegisterListenerRequest struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+
+	RegisterListenerResponse struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+
+	FollowArtistRequest struct {
+		ArtistID string `json:"artist_id"`
+	}
+
+	FollowArtistResponse struct {
+		ListenerID string    `json:"listener_id"`
+		ArtistID   string    `json:"artist_id"`
+		FollowedAt time.Time `json:"followed_at"`
+	}
+
+	LikeSongRequest struct {
 		SongID string `json:"song_id"`
 	}
+
+	LikeSongResponse struct {
+		ListenerID string    `json:"listener_id"`
+		SongID     string    `json:"song_id"`
+		LikedAt    time.Time `json:"liked_at"`
+	}
+
+	RateAlbumRequest struct {
+		AlbumID string `json:"album_id"`
+		Rating  int    `json:"rating"`
+	}
+
+	RateAlbumResponse struct {
+		ListenerID string    `json:"listener_id"`
+		AlbumID    string    `json:"album_id"`
+		Rating     int       `json:"rating"`
+		RatedAt    time.Time `json:"rated_at"`
+	}
+
+	CreatePlaylistRequest struct {
+		Name       string `json:"name"`
+		ListenerID string `json:"listener_id"`
+	}
+
+	RenamePlaylistRequest struct {
+		Name string `json:"name"`
+	}
+
+	AddSongToPlaylistRequest struct {
+		SongID string `json:"song_id"`
+	}
+
+	ReorderPlaylistRequest struct {
+		SongIDs []string `json:"song_ids"`
+	}
+
+	PlaylistResponse struct {
+		ID         string   `json:"id"`
+		Name       string   `json:"name"`
+		ListenerID string   `json:"listener_id"`
+		SongIDs    []string `json:"song_ids"`
+	}
 )
 
 func (r SubscribeArtistRequest) ToCommand() command.SubscribeArtistCommand {
 	return command.SubscribeArtistCommand{
 		Name:   r.Name,
 		Gender: song.Gender(r.Gender),
+		Genres: r.Genres,
 	}
 }
 
-func (r PublishAlbumRequest) ToCommand() command.PublishAlbumCommand {
-	return command.PublishAlbumCommand{
+func (r MergeArtistsRequest) ToCommand(version int) command.MergeArtistsCommand {
+	return command.MergeArtistsCommand{
+		SourceID: r.SourceID,
+		TargetID: r.TargetID,
+		Version:  version,
+	}
+}
+
+func (r CreateAlbumRequest) ToCommand() command.CreateAlbumCommand {
+	return command.CreateAlbumCommand{
 		Title:       r.Title,
 		ArtistID:    r.ArtistID,
 		ReleaseYear: r.ReleaseYear,
+		Genres:      r.Genres,
+	}
+}
+
+func (r ScheduleAlbumRequest) ToCommand(albumID string, version int) command.ScheduleAlbumCommand {
+	return command.ScheduleAlbumCommand{
+		AlbumID:     albumID,
+		ReleaseDate: r.ReleaseDate,
+		Version:     version,
 	}
 }
 
 func (r PublishSongRequest) ToCommand() command.PublishSongCommand {
+	featuring := make([]command.FeaturedArtist, len(r.Featuring), len(r.Featuring))
+	for i, f := range r.Featuring {
+		featuring[i] = command.FeaturedArtist{
+			ArtistID: f.ArtistID,
+			Role:     song.Role(f.Role),
+		}
+	}
+
+	discNumber := r.DiscNumber
+	if discNumber == 0 {
+		discNumber = 1
+	}
+
 	return command.PublishSongCommand{
 		TrackNumber: r.TrackNumber,
+		DiscNumber:  discNumber,
 		Title:       r.Title,
 		AlbumID:     r.AlbumID,
+		Duration:    time.Duration(r.DurationMs) * time.Millisecond,
+		ISRC:        r.ISRC,
+		Explicit:    r.Explicit,
+		Composers:   r.Composers,
+		Featuring:   featuring,
+	}
+}
+
+func (r PlaySongRequest) ToCommand() command.PlaySongCommand {
+	return command.PlaySongCommand{
+		SongID:     r.SongID,
+		ListenerID: r.ListenerID,
+	}
+}
+
+func (r RegisterListenerRequest) ToCommand() command.RegisterListenerCommand {
+	return command.RegisterListenerCommand{
+		Name:  r.Name,
+		Email: r.Email,
+	}
+}
+
+func (r FollowArtistRequest) ToCommand(listenerID string) command.FollowArtistCommand {
+	return command.FollowArtistCommand{
+		ListenerID: listenerID,
+		ArtistID:   r.ArtistID,
+	}
+}
+
+func (r LikeSongRequest) ToCommand(listenerID string) command.LikeSongCommand {
+	return command.LikeSongCommand{
+		ListenerID: listenerID,
+		SongID:     r.SongID,
+	}
+}
+
+func (r RateAlbumRequest) ToCommand(listenerID string) command.RateAlbumCommand {
+	return command.RateAlbumCommand{
+		ListenerID: listenerID,
+		AlbumID:    r.AlbumID,
+		Rating:     r.Rating,
+	}
+}
+
+func (r CreatePlaylistRequest) ToCommand() command.CreatePlaylistCommand {
+	return command.CreatePlaylistCommand{
+		Name:       r.Name,
+		ListenerID: r.ListenerID,
+	}
+}
+
+func (r RenamePlaylistRequest) ToCommand(playlistID string, version int) command.RenamePlaylistCommand {
+	return command.RenamePlaylistCommand{
+		PlaylistID: playlistID,
+		Name:       r.Name,
+		Version:    version,
+	}
+}
+
+func (r AddSongToPlaylistRequest) ToCommand(playlistID string, version int) command.AddSongToPlaylistCommand {
+	return command.AddSongToPlaylistCommand{
+		PlaylistID: playlistID,
+		SongID:     r.SongID,
+		Version:    version,
+	}
+}
+
+func (r ReorderPlaylistRequest) ToCommand(playlistID string, version int) command.ReorderPlaylistCommand {
+	return command.ReorderPlaylistCommand{
+		PlaylistID: playlistID,
+		SongIDs:    r.SongIDs,
+		Version:    version,
 	}
 }
 
@@ -81,30 +319,138 @@ func NewSubscribeArtistResponseFromDomain(artist song.Artist) SubscribeArtistRes
 		ID:     artist.ID,
 		Name:   artist.Name,
 		Gender: string(artist.Gender),
+		Genres: song.GenreNames(artist.Genres),
+	}
+}
+
+func NewMergeArtistsResponseFromDomain(merge song.ArtistMerge) MergeArtistsResponse {
+	return MergeArtistsResponse{
+		SourceID: merge.SourceID,
+		Target:   NewSubscribeArtistResponseFromDomain(merge.Target),
+		MergedAt: merge.MergedAt,
 	}
 }
 
-func NewPublishAlbumResponseFromDomain(album song.Album) PublishAlbumResponse {
-	return PublishAlbumResponse{
+func NewCreateAlbumResponseFromDomain(album song.Album) CreateAlbumResponse {
+	return CreateAlbumResponse{
 		AlbumResponse: AlbumResponse{
 			ID:          album.ID,
 			Title:       album.Title,
 			ReleaseYear: album.ReleaseYear,
+			Status:      string(album.Status),
+			ReleaseDate: releaseDate(album),
+			Genres:      song.GenreNames(album.Genres),
 		},
 		Artist: NewSubscribeArtistResponseFromDomain(album.Artist),
 	}
 }
 
 func NewPublishSongResponseFromDomain(song song.Song) PublishSongResponse {
+	featuring := make([]FeaturedArtistResponse, len(song.Featuring), len(song.Featuring))
+	for i, f := range song.Featuring {
+		featuring[i] = FeaturedArtistResponse{
+			Artist: NewSubscribeArtistResponseFromDomain(f.Artist),
+			Role:   string(f.Role),
+		}
+	}
+
 	return PublishSongResponse{
 		ID:          song.ID,
 		TrackNumber: song.TrackNumber,
+		DiscNumber:  song.DiscNumber,
 		Title:       song.Title,
+		DurationMs:  song.Duration.Milliseconds(),
+		ISRC:        song.ISRC,
+		Explicit:    song.Explicit,
+		Composers:   song.Composers,
 		Album: AlbumResponse{
 			ID:          song.Album.ID,
 			Title:       song.Album.Title,
 			ReleaseYear: song.Album.ReleaseYear,
 		},
-		Artist: NewSubscribeArtistResponseFromDomain(song.Artist),
+		Artist:    NewSubscribeArtistResponseFromDomain(song.Artist),
+		Featuring: featuring,
+	}
+}
+
+func NewRegisterListenerResponseFromDomain(listener song.Listener) RegisterListenerResponse {
+	return RegisterListenerResponse{
+		ID:    listener.ID,
+		Name:  listener.Name,
+		Email: listener.Email,
+	}
+}
+
+func NewPlaylistResponseFromDomain(playlist song.Playlist) PlaylistResponse {
+	songIDs := make([]string, len(playlist.Songs), len(playlist.Songs))
+	for i, s := range playlist.Songs {
+		songIDs[i] = s.ID
+	}
+
+	return PlaylistResponse{
+		ID:         playlist.ID,
+		Name:       playlist.Name,
+		ListenerID: playlist.ListenerID,
+		SongIDs:    songIDs,
+	}
+}
+
+func NewFollowArtistResponseFromDomain(follow song.Follow) FollowArtistResponse {
+	return FollowArtistResponse{
+		ListenerID: follow.ListenerID,
+		ArtistID:   follow.ArtistID,
+		FollowedAt: follow.FollowedAt,
+	}
+}
+
+func NewLikeSongResponseFromDomain(like song.Like) LikeSongResponse {
+	return LikeSongResponse{
+		ListenerID: like.ListenerID,
+		SongID:     like.Song.ID,
+		LikedAt:    like.LikedAt,
+	}
+}
+
+func NewRateAlbumResponseFromDomain(rating song.AlbumRating) RateAlbumResponse {
+	return RateAlbumResponse{
+		ListenerID: rating.ListenerID,
+		AlbumID:    rating.AlbumID,
+		Rating:     rating.Rating,
+		RatedAt:    rating.RatedAt,
+	}
+}
+
+func NewImportJobResponseFromDomain(job song.ImportJob) ImportJobResponse {
+	rows := make([]ImportRowResponse, len(job.Rows), len(job.Rows))
+	for i, row := range job.Rows {
+		rows[i] = ImportRowResponse{
+			Line:       row.Line,
+			Type:       string(row.Record.Type),
+			Key:        row.Record.Key(),
+			Status:     string(row.Status),
+			ResourceID: row.ResourceID,
+			Error:      row.Error,
+		}
+	}
+
+	return ImportJobResponse{
+		ID:        job.ID,
+		Format:    job.Format,
+		Status:    string(job.Status),
+		Total:     len(job.Rows),
+		Pending:   job.Count(song.RowPending),
+		Succeeded: job.Count(song.RowSucceeded),
+		Failed:    job.Count(song.RowFailed),
+		Rows:      rows,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
 	}
 }
+
+func releaseDate(album song.Album) *time.Time {
+	if album.ReleaseDate.IsZero() {
+		return nil
+	}
+
+	return &album.ReleaseDate
+}
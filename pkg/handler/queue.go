@@ -6,16 +6,21 @@ import (
 	"cqrs-sample/pkg/message"
 	"cqrs-sample/pkg/song"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 )
 
 type (
 	ArtistDatabase interface {
 		CreateArtist(ctx context.Context, artist song.Artist) error
+		MergeArtists(ctx context.Context, merge song.ArtistMerge) error
 	}
 
 	AlbumDatabase interface {
-		CreateAlbum(ctx context.Context, album song.Album) error
+		SaveAlbum(ctx context.Context, album song.Album) (bool, error)
+		GetFollowerIDs(ctx context.Context, artistID string) ([]string, error)
+		AddReleaseToFeeds(ctx context.Context, listenerIDs []string, album song.Album, publishedAt time.Time) error
 	}
 
 	SongDatabase interface {
@@ -24,10 +29,49 @@ type (
 		IncrementSongPlays(ctx context.Context, songID string) error
 	}
 
+	ListenerDatabase interface {
+		CreateListener(ctx context.Context, listener song.Listener) error
+	}
+
+	FollowDatabase interface {
+		CreateFollow(ctx context.Context, follow song.Follow) error
+	}
+
+	LikeDatabase interface {
+		GetSongByID(ctx context.Context, id string) (song.Song, error)
+		AddLike(ctx context.Context, like song.Like) error
+	}
+
+	RatingDatabase interface {
+		AddAlbumRating(ctx context.Context, rating song.AlbumRating) error
+	}
+
+	ListeningDatabase interface {
+		GetSongByID(ctx context.Context, id string) (song.Song, error)
+		AddListening(ctx context.Context, listening song.Listening) error
+	}
+
+	PlaylistDatabase interface {
+		GetSongsByIDs(ctx context.Context, ids []string) ([]song.Song, error)
+		SavePlaylist(ctx context.Context, playlist song.Playlist) error
+	}
+
+	CacheStore interface {
+		Delete(ctx context.Context, keys ...string) error
+	}
+
 	ArtistSubscribed struct {
 		db ArtistDatabase
 	}
 
+	ArtistMerged struct {
+		db ArtistDatabase
+	}
+
+	AlbumChanged struct {
+		db AlbumDatabase
+	}
+
 	AlbumPublished struct {
 		db AlbumDatabase
 	}
@@ -39,6 +83,34 @@ type (
 	IncrementSongPlays struct {
 		db SongDatabase
 	}
+
+	ListenerRegistered struct {
+		db ListenerDatabase
+	}
+
+	RecordListening struct {
+		db ListeningDatabase
+	}
+
+	PlaylistChanged struct {
+		db PlaylistDatabase
+	}
+
+	ArtistFollowed struct {
+		db FollowDatabase
+	}
+
+	SongLiked struct {
+		db LikeDatabase
+	}
+
+	AlbumRated struct {
+		db RatingDatabase
+	}
+
+	CacheInvalidated struct {
+		store CacheStore
+	}
 )
 
 func NewArtistSubscribed(db ArtistDatabase) *ArtistSubscribed {
@@ -47,6 +119,18 @@ func NewArtistSubscribed(db ArtistDatabase) *ArtistSubscribed {
 	}
 }
 
+func NewArtistMerged(db ArtistDatabase) *ArtistMerged {
+	return &ArtistMerged{
+		db: db,
+	}
+}
+
+func NewAlbumChanged(db AlbumDatabase) *AlbumChanged {
+	return &AlbumChanged{
+		db: db,
+	}
+}
+
 func NewAlbumPublished(db AlbumDatabase) *AlbumPublished {
 	return &AlbumPublished{
 		db: db,
@@ -65,6 +149,48 @@ func NewIncrementSongPlays(db SongDatabase) *IncrementSongPlays {
 	}
 }
 
+func NewListenerRegistered(db ListenerDatabase) *ListenerRegistered {
+	return &ListenerRegistered{
+		db: db,
+	}
+}
+
+func NewRecordListening(db ListeningDatabase) *RecordListening {
+	return &RecordListening{
+		db: db,
+	}
+}
+
+func NewPlaylistChanged(db PlaylistDatabase) *PlaylistChanged {
+	return &PlaylistChanged{
+		db: db,
+	}
+}
+
+func NewArtistFollowed(db FollowDatabase) *ArtistFollowed {
+	return &ArtistFollowed{
+		db: db,
+	}
+}
+
+func NewSongLiked(db LikeDatabase) *SongLiked {
+	return &SongLiked{
+		db: db,
+	}
+}
+
+func NewAlbumRated(db RatingDatabase) *AlbumRated {
+	return &AlbumRated{
+		db: db,
+	}
+}
+
+func NewCacheInvalidated(store CacheStore) *CacheInvalidated {
+	return &CacheInvalidated{
+		store: store,
+	}
+}
+
 func (ah ArtistSubscribed) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
 	artist, err := unmarshal[message.Artist](body)
 	if err != nil {
@@ -74,13 +200,58 @@ func (ah ArtistSubscribed) Handle(ctx context.Context, body []byte, _ map[string
 	return ah.db.CreateArtist(ctx, artist.ToDomain())
 }
 
-func (ap AlbumPublished) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
+func (am ArtistMerged) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
+	merge, err := unmarshal[message.ArtistMerge](body)
+	if err != nil {
+		return err
+	}
+
+	return am.db.MergeArtists(ctx, merge.ToDomain())
+}
+
+func (ac AlbumChanged) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
 	album, err := unmarshal[message.Album](body)
 	if err != nil {
 		return err
 	}
 
-	return ap.db.CreateAlbum(ctx, album.ToDomain())
+	_, err = ac.db.SaveAlbum(ctx, album.ToDomain())
+	return err
+}
+
+func (ac AlbumChanged) Provides(body []byte) []string {
+	return providesAlbum(body)
+}
+
+func (ap AlbumPublished) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
+	msg, err := unmarshal[message.Album](body)
+	if err != nil {
+		return err
+	}
+
+	// fan out only once the album is at this version, so a stale or
+	// rescheduled release never reaches the feeds
+	album := msg.ToDomain()
+	current, err := ap.db.SaveAlbum(ctx, album)
+	if err != nil || !current {
+		return err
+	}
+
+	followerIDs, err := ap.db.GetFollowerIDs(ctx, album.Artist.ID)
+	if err != nil {
+		return err
+	}
+
+	publishedAt := album.ReleaseDate
+	if publishedAt.IsZero() {
+		publishedAt = time.Now().UTC()
+	}
+
+	return ap.db.AddReleaseToFeeds(ctx, followerIDs, album, publishedAt)
+}
+
+func (ap AlbumPublished) Provides(body []byte) []string {
+	return providesAlbum(body)
 }
 
 func (sp SongPublished) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
@@ -93,7 +264,17 @@ func (sp SongPublished) Handle(ctx context.Context, body []byte, _ map[string]in
 		return err
 	}
 
-	return sp.db.AddSongToAlbum(ctx, s.ToDomain())
+	err = sp.db.AddSongToAlbum(ctx, s.ToDomain())
+	return dependencyErr(err, albumDependency(s.Album.ID))
+}
+
+func (sp SongPublished) Provides(body []byte) []string {
+	s, err := unmarshal[message.Song](body)
+	if err != nil {
+		return nil
+	}
+
+	return []string{songDependency(s.ID)}
 }
 
 func (a IncrementSongPlays) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
@@ -102,7 +283,134 @@ func (a IncrementSongPlays) Handle(ctx context.Context, body []byte, _ map[strin
 		return err
 	}
 
-	return a.db.IncrementSongPlays(ctx, ps.SongID)
+	err = a.db.IncrementSongPlays(ctx, ps.SongID)
+	return dependencyErr(err, songDependency(ps.SongID))
+}
+
+func (lr ListenerRegistered) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
+	listener, err := unmarshal[message.Listener](body)
+	if err != nil {
+		return err
+	}
+
+	return lr.db.CreateListener(ctx, listener.ToDomain())
+}
+
+func (rl RecordListening) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
+	ps, err := unmarshal[message.PlaySong](body)
+	if err != nil {
+		return err
+	}
+
+	if ps.ListenerID == "" {
+		return nil
+	}
+
+	s, err := rl.db.GetSongByID(ctx, ps.SongID)
+	if err != nil {
+		return dependencyErr(err, songDependency(ps.SongID))
+	}
+
+	return rl.db.AddListening(ctx, song.Listening{
+		ID:         ps.ID,
+		ListenerID: ps.ListenerID,
+		Song:       s,
+		PlayedAt:   ps.PlayedAt,
+	})
+}
+
+func (pc PlaylistChanged) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
+	p, err := unmarshal[message.Playlist](body)
+	if err != nil {
+		return err
+	}
+
+	songs, err := pc.db.GetSongsByIDs(ctx, p.SongIDs)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]song.Song, len(songs))
+	for _, s := range songs {
+		byID[s.ID] = s
+	}
+
+	playlist := p.ToDomain()
+	for i, s := range playlist.Songs {
+		found, ok := byID[s.ID]
+		if !ok {
+			return DependencyErr{Dependency: songDependency(s.ID)}
+		}
+
+		playlist.Songs[i] = found
+	}
+
+	return pc.db.SavePlaylist(ctx, playlist)
+}
+
+func (af ArtistFollowed) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
+	follow, err := unmarshal[message.Follow](body)
+	if err != nil {
+		return err
+	}
+
+	return af.db.CreateFollow(ctx, follow.ToDomain())
+}
+
+func (sl SongLiked) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
+	like, err := unmarshal[message.Like](body)
+	if err != nil {
+		return err
+	}
+
+	s, err := sl.db.GetSongByID(ctx, like.SongID)
+	if err != nil {
+		return dependencyErr(err, songDependency(like.SongID))
+	}
+
+	return sl.db.AddLike(ctx, song.Like{
+		ListenerID: like.ListenerID,
+		Song:       s,
+		LikedAt:    like.LikedAt,
+	})
+}
+
+func (ar AlbumRated) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
+	rating, err := unmarshal[message.AlbumRating](body)
+	if err != nil {
+		return err
+	}
+
+	err = ar.db.AddAlbumRating(ctx, rating.ToDomain())
+	return dependencyErr(err, albumDependency(rating.AlbumID))
+}
+
+func (ci CacheInvalidated) Handle(ctx context.Context, body []byte, _ map[string]interface{}) error {
+	invalidation, err := unmarshal[message.CacheInvalidation](body)
+	if err != nil {
+		return err
+	}
+
+	return ci.store.Delete(ctx, invalidation.Keys...)
+}
+
+func providesAlbum(body []byte) []string {
+	album, err := unmarshal[message.Album](body)
+	if err != nil {
+		return nil
+	}
+
+	return []string{albumDependency(album.ID)}
+}
+
+// dependencyErr turns a lookup that found nothing into a DependencyErr, so
+// the event is parked until dependency is projected.
+func dependencyErr(err error, dependency string) error {
+	if errors.Is(err, song.NotFoundErr) {
+		return DependencyErr{Dependency: dependency}
+	}
+
+	return err
 }
 
 func unmarshal[T any](body []byte) (T, error) {
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"context"
+	"cqrs-sample/pkg/message"
+	"cqrs-sample/pkg/song"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type (
+	fakeAlbumDatabase struct {
+		followers map[string][]string
+		stale     bool
+		feedErr   error
+		calls     []string
+		fedTo     []string
+		fedAt     time.Time
+	}
+
+	fakeFollowDatabase struct {
+		follows []song.Follow
+	}
+)
+
+func (f *fakeAlbumDatabase) SaveAlbum(_ context.Context, album song.Album) (bool, error) {
+	f.calls = append(f.calls, "save "+album.ID)
+	return !f.stale, nil
+}
+
+func (f *fakeAlbumDatabase) GetFollowerIDs(_ context.Context, artistID string) ([]string, error) {
+	return f.followers[artistID], nil
+}
+
+func (f *fakeAlbumDatabase) AddReleaseToFeeds(_ context.Context, listenerIDs []string, album song.Album, publishedAt time.Time) error {
+	f.calls = append(f.calls, "feed "+album.ID)
+	f.fedTo, f.fedAt = listenerIDs, publishedAt
+	return f.feedErr
+}
+
+func (f *fakeFollowDatabase) CreateFollow(_ context.Context, follow song.Follow) error {
+	f.follows = append(f.follows, follow)
+	return nil
+}
+
+func publishedAlbumBody(t *testing.T, releaseDate time.Time) []byte {
+	t.Helper()
+
+	body, err := json.Marshal(message.NewAlbumFromDomain(song.Album{
+		ID:          "al1",
+		Title:       "Some Album",
+		Artist:      song.Artist{ID: "ar1"},
+		Status:      song.PublishedStatus,
+		ReleaseDate: releaseDate,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return body
+}
+
+func Test_Album_Published_Fans_Out_To_Followers_Feeds(t *testing.T) {
+	// Arrange
+	releaseDate := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	db := &fakeAlbumDatabase{followers: map[string][]string{"ar1": {"l1", "l2"}}}
+
+	// Act
+	err := NewAlbumPublished(db).Handle(context.Background(), publishedAlbumBody(t, releaseDate), nil)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("err: got = %v, want = nil", err)
+	}
+
+	if want := []string{"l1", "l2"}; !reflect.DeepEqual(db.fedTo, want) {
+		t.Errorf("feeds: got = %v, want = %v", db.fedTo, want)
+	}
+
+	if !db.fedAt.Equal(releaseDate) {
+		t.Errorf("published at: got = %v, want = %v", db.fedAt, releaseDate)
+	}
+
+	if want := []string{"save al1", "feed al1"}; !reflect.DeepEqual(db.calls, want) {
+		t.Errorf("calls: got = %v, want = %v", db.calls, want)
+	}
+}
+
+func Test_Stale_Album_Published_Is_Not_Fanned_Out(t *testing.T) {
+	// Arrange
+	db := &fakeAlbumDatabase{followers: map[string][]string{"ar1": {"l1"}}, stale: true}
+
+	// Act
+	err := NewAlbumPublished(db).Handle(context.Background(), publishedAlbumBody(t, time.Now()), nil)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("err: got = %v, want = nil", err)
+	}
+
+	if want := []string{"save al1"}; !reflect.DeepEqual(db.calls, want) {
+		t.Errorf("calls: got = %v, want = %v", db.calls, want)
+	}
+}
+
+func Test_Album_Published_Returns_Fan_Out_Error(t *testing.T) {
+	// Arrange
+	feedErr := errors.New("timeout")
+	db := &fakeAlbumDatabase{followers: map[string][]string{"ar1": {"l1"}}, feedErr: feedErr}
+
+	// Act
+	err := NewAlbumPublished(db).Handle(context.Background(), publishedAlbumBody(t, time.Now()), nil)
+
+	// Assert
+	if !errors.Is(err, feedErr) {
+		t.Errorf("err: got = %v, want = %v", err, feedErr)
+	}
+
+	if want := []string{"save al1", "feed al1"}; !reflect.DeepEqual(db.calls, want) {
+		t.Errorf("calls: got = %v, want = %v", db.calls, want)
+	}
+}
+
+func Test_Artist_Followed_Creates_Follow(t *testing.T) {
+	// Arrange
+	followedAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	follow := song.Follow{ListenerID: "l1", ArtistID: "ar1", FollowedAt: followedAt}
+	body, err := json.Marshal(message.NewFollowFromDomain(follow))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := &fakeFollowDatabase{}
+
+	// Act
+	err = NewArtistFollowed(db).Handle(context.Background(), body, nil)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("err: got = %v, want = nil", err)
+	}
+
+	if want := []song.Follow{follow}; !reflect.DeepEqual(db.follows, want) {
+		t.Errorf("follows: got = %v, want = %v", db.follows, want)
+	}
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"cqrs-sample/pkg/event"
+	"errors"
+	"time"
+)
+
+type (
+	// RetryPolicy retries a failing event in process, waiting Backoff
+	// between Attempts, before handing it back to the broker. Invalid
+	// payloads are never retried.
+	RetryPolicy struct {
+		Attempts int
+		Backoff  time.Duration
+	}
+
+	// Options tune how a registered handler consumes its queue. Zero values
+	// fall back to the registry defaults; handlers are ordered by aggregate
+	// unless Unordered is set. Timeout bounds a single attempt.
+	Options struct {
+		Prefetch    int
+		Concurrency int
+		Unordered   bool
+		Timeout     time.Duration
+		Retry       RetryPolicy
+	}
+
+	// Registration binds the events a handler projects to the queue it
+	// consumes them from.
+	Registration struct {
+		Queue   string
+		Events  []event.Event
+		Handler QueueHandler
+		Options
+	}
+
+	// Registry lists the worker projections, from which the worker declares
+	// its queues and bindings and starts one subscription per queue.
+	Registry struct {
+		defaults      Options
+		registrations []Registration
+	}
+
+	Retrying struct {
+		next   QueueHandler
+		policy RetryPolicy
+	}
+)
+
+func NewRegistry(defaults Options) *Registry {
+	return &Registry{
+		defaults: defaults,
+	}
+}
+
+func NewRetrying(next QueueHandler, policy RetryPolicy) *Retrying {
+	return &Retrying{
+		next:   next,
+		policy: policy,
+	}
+}
+
+// Register routes events to h through queue, which must be unique.
+func (r *Registry) Register(queue string, h QueueHandler, opts Options, events ...event.Event) {
+	r.registrations = append(r.registrations, Registration{
+		Queue:   queue,
+		Events:  events,
+		Handler: h,
+		Options: opts,
+	})
+}
+
+// Registrations returns the registered handlers with the defaults applied.
+func (r *Registry) Registrations() []Registration {
+	registrations := make([]Registration, len(r.registrations), len(r.registrations))
+	for i, registration := range r.registrations {
+		if registration.Prefetch <= 0 {
+			registration.Prefetch = r.defaults.Prefetch
+		}
+
+		if registration.Concurrency <= 0 {
+			registration.Concurrency = r.defaults.Concurrency
+		}
+
+		if registration.Timeout <= 0 {
+			registration.Timeout = r.defaults.Timeout
+		}
+
+		if registration.Retry.Attempts <= 0 {
+			registration.Retry = r.defaults.Retry
+		}
+
+		registrations[i] = registration
+	}
+
+	return registrations
+}
+
+func (rh Retrying) Handle(ctx context.Context, body []byte, headers map[string]interface{}) error {
+	err := rh.next.Handle(ctx, body, headers)
+	for attempt := 1; attempt < rh.policy.Attempts && err != nil; attempt++ {
+		if errors.Is(err, event.InvalidPayloadErr) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(rh.policy.Backoff):
+		}
+
+		err = rh.next.Handle(ctx, body, headers)
+	}
+
+	return err
+}
@@ -2,42 +2,126 @@ package message
 
 import (
 	"cqrs-sample/pkg/song"
+	"time"
 )
 
 type (
 	Song struct {
-		ID          string `json:"id"`
-		TrackNumber int    `json:"track_number"`
-		Title       string `json:"title"`
-		Album       Album  `json:"album"`
-		Artist      Artist `json:"artist"`
+		ID          string      `json:"id"`
+		TrackNumber int         `json:"track_number"`
+		DiscNumber  int         `json:"disc_number"`
+		Title       string      `json:"title"`
+		DurationMs  int64       `json:"duration_ms"`
+		ISRC        string      `json:"isrc,omitempty"`
+		Explicit    bool        `json:"explicit"`
+		Composers   []string    `json:"composers"`
+		Album       Album       `json:"album"`
+		Artist      Artist      `json:"artist"`
+		Featuring   []Featuring `json:"featuring"`
+		Version     int         `json:"version"`
+	}
+
+	Featuring struct {
+		Artist Artist `json:"artist"`
+		Role   string `json:"role"`
 	}
 
 	Album struct {
-		ID          string `json:"id"`
-		Title       string `json:"title"`
-		Artist      Artist `json:"artist"`
-		ReleaseYear int    `json:"release_year"`
+		ID          string    `json:"id"`
+		Title       string    `json:"title"`
+		Artist      Artist    `json:"artist"`
+		ReleaseYear int       `json:"release_year"`
+		Status      string    `json:"status"`
+		ReleaseDate time.Time `json:"release_date"`
+		Genres      []string  `json:"genres"`
+		Version     int       `json:"version"`
 	}
 
 	Artist struct {
-		ID     string `json:"id"`
-		Name   string `json:"name"`
-		Gender string `json:"gender"`
+		ID      string   `json:"id"`
+		Name    string   `json:"name"`
+		Gender  string   `json:"gender"`
+		Genres  []string `json:"genres"`
+		Version int      `json:"version"`
+	}
+
+	Listener struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+
+	Playlist struct {
+		ID         string   `json:"id"`
+		Name       string   `json:"name"`
+		ListenerID string   `json:"listener_id"`
+		SongIDs    []string `json:"song_ids"`
+		Version    int      `json:"version"`
+	}
+
+	ArtistMerge struct {
+		SourceID string    `json:"source_id"`
+		Target   Artist    `json:"target"`
+		MergedAt time.Time `json:"merged_at"`
+	}
+
+	CacheInvalidation struct {
+		Keys []string `json:"keys"`
+	}
+
+	Follow struct {
+		ListenerID string    `json:"listener_id"`
+		ArtistID   string    `json:"artist_id"`
+		FollowedAt time.Time `json:"followed_at"`
+	}
+
+	Like struct {
+		ListenerID string    `json:"listener_id"`
+		SongID     string    `json:"song_id"`
+		LikedAt    time.Time `json:"liked_at"`
+	}
+
+	AlbumRating struct {
+		ListenerID string    `json:"listener_id"`
+		AlbumID    string    `json:"album_id"`
+		Rating     int       `json:"rating"`
+		RatedAt    time.Time `json:"rated_at"`
 	}
 
 	PlaySong struct {
-		SongID string `json:"song_id"`
+		ID         string    `json:"id"`
+		SongID     string    `json:"song_id"`
+		ListenerID string    `json:"listener_id,omitempty"`
+		PlayedAt   time.Time `json:"played_at"`
 	}
 )
 
 func (s Song) ToDomain() song.Song {
+	var featuring []song.Featuring
+	for _, f := range s.Featuring {
+		featuring = append(featuring, f.ToDomain())
+	}
+
 	return song.Song{
 		ID:          s.ID,
 		TrackNumber: s.TrackNumber,
+		DiscNumber:  s.DiscNumber,
 		Title:       s.Title,
+		Duration:    time.Duration(s.DurationMs) * time.Millisecond,
+		ISRC:        s.ISRC,
+		Explicit:    s.Explicit,
+		Composers:   s.Composers,
 		Album:       s.Album.ToDomain(),
 		Artist:      s.Artist.ToDomain(),
+		Featuring:   featuring,
+		Version:     s.Version,
+	}
+}
+
+func (f Featuring) ToDomain() song.Featuring {
+	return song.Featuring{
+		Artist: f.Artist.ToDomain(),
+		Role:   song.Role(f.Role),
 	}
 }
 
@@ -47,24 +131,97 @@ func (a Album) ToDomain() song.Album {
 		Title:       a.Title,
 		Artist:      a.Artist.ToDomain(),
 		ReleaseYear: a.ReleaseYear,
+		Status:      song.AlbumStatus(a.Status),
+		ReleaseDate: a.ReleaseDate,
+		Genres:      song.NewGenres(a.Genres),
+		Version:     a.Version,
 	}
 }
 
 func (a Artist) ToDomain() song.Artist {
 	return song.Artist{
-		ID:     a.ID,
-		Name:   a.Name,
-		Gender: song.Gender(a.Gender),
+		ID:      a.ID,
+		Name:    a.Name,
+		Gender:  song.Gender(a.Gender),
+		Genres:  song.NewGenres(a.Genres),
+		Version: a.Version,
+	}
+}
+
+func (l Listener) ToDomain() song.Listener {
+	return song.Listener{
+		ID:    l.ID,
+		Name:  l.Name,
+		Email: l.Email,
+	}
+}
+
+func (p Playlist) ToDomain() song.Playlist {
+	songs := make([]song.Song, len(p.SongIDs), len(p.SongIDs))
+	for i, id := range p.SongIDs {
+		songs[i] = song.Song{ID: id}
+	}
+
+	return song.Playlist{
+		ID:         p.ID,
+		Name:       p.Name,
+		ListenerID: p.ListenerID,
+		Songs:      songs,
+		Version:    p.Version,
+	}
+}
+
+func (m ArtistMerge) ToDomain() song.ArtistMerge {
+	return song.ArtistMerge{
+		SourceID: m.SourceID,
+		Target:   m.Target.ToDomain(),
+		MergedAt: m.MergedAt,
+	}
+}
+
+func (f Follow) ToDomain() song.Follow {
+	return song.Follow{
+		ListenerID: f.ListenerID,
+		ArtistID:   f.ArtistID,
+		FollowedAt: f.FollowedAt,
+	}
+}
+
+func (r AlbumRating) ToDomain() song.AlbumRating {
+	return song.AlbumRating{
+		ListenerID: r.ListenerID,
+		AlbumID:    r.AlbumID,
+		Rating:     r.Rating,
+		RatedAt:    r.RatedAt,
 	}
 }
 
 func NewSongFromDomain(s song.Song) Song {
+	featuring := make([]Featuring, len(s.Featuring), len(s.Featuring))
+	for i, f := range s.Featuring {
+		featuring[i] = NewFeaturingFromDomain(f)
+	}
+
 	return Song{
 		ID:          s.ID,
 		TrackNumber: s.TrackNumber,
+		DiscNumber:  s.DiscNumber,
 		Title:       s.Title,
+		DurationMs:  s.Duration.Milliseconds(),
+		ISRC:        s.ISRC,
+		Explicit:    s.Explicit,
+		Composers:   s.Composers,
 		Album:       NewAlbumFromDomain(s.Album),
 		Artist:      NewArtistFromDomain(s.Artist),
+		Featuring:   featuring,
+		Version:     s.Version,
+	}
+}
+
+func NewFeaturingFromDomain(f song.Featuring) Featuring {
+	return Featuring{
+		Artist: NewArtistFromDomain(f.Artist),
+		Role:   string(f.Role),
 	}
 }
 
@@ -74,13 +231,75 @@ func NewAlbumFromDomain(album song.Album) Album {
 		Title:       album.Title,
 		Artist:      NewArtistFromDomain(album.Artist),
 		ReleaseYear: album.ReleaseYear,
+		Status:      string(album.Status),
+		ReleaseDate: album.ReleaseDate,
+		Genres:      song.GenreNames(album.Genres),
+		Version:     album.Version,
 	}
 }
 
 func NewArtistFromDomain(artist song.Artist) Artist {
 	return Artist{
-		ID:     artist.ID,
-		Name:   artist.Name,
-		Gender: string(artist.Gender),
+		ID:      artist.ID,
+		Name:    artist.Name,
+		Gender:  string(artist.Gender),
+		Genres:  song.GenreNames(artist.Genres),
+		Version: artist.Version,
+	}
+}
+
+func NewListenerFromDomain(listener song.Listener) Listener {
+	return Listener{
+		ID:    listener.ID,
+		Name:  listener.Name,
+		Email: listener.Email,
+	}
+}
+
+func NewPlaylistFromDomain(playlist song.Playlist) Playlist {
+	songIDs := make([]string, len(playlist.Songs), len(playlist.Songs))
+	for i, s := range playlist.Songs {
+		songIDs[i] = s.ID
+	}
+
+	return Playlist{
+		ID:         playlist.ID,
+		Name:       playlist.Name,
+		ListenerID: playlist.ListenerID,
+		SongIDs:    songIDs,
+		Version:    playlist.Version,
+	}
+}
+
+func NewArtistMergeFromDomain(merge song.ArtistMerge) ArtistMerge {
+	return ArtistMerge{
+		SourceID: merge.SourceID,
+		Target:   NewArtistFromDomain(merge.Target),
+		MergedAt: merge.MergedAt,
+	}
+}
+
+func NewFollowFromDomain(follow song.Follow) Follow {
+	return Follow{
+		ListenerID: follow.ListenerID,
+		ArtistID:   follow.ArtistID,
+		FollowedAt: follow.FollowedAt,
+	}
+}
+
+func NewLikeFromDomain(like song.Like) Like {
+	return Like{
+		ListenerID: like.ListenerID,
+		SongID:     like.Song.ID,
+		LikedAt:    like.LikedAt,
+	}
+}
+
+func NewAlbumRatingFromDomain(rating song.AlbumRating) AlbumRating {
+	return AlbumRating{
+		ListenerID: rating.ListenerID,
+		AlbumID:    rating.AlbumID,
+		Rating:     rating.Rating,
+		RatedAt:    rating.RatedAt,
 	}
 }
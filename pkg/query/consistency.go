@@ -0,0 +1,149 @@
+package query
+
+import (
+	"context"
+	"cqrs-sample/pkg/song"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ArtistToken   TokenKind = "artist"
+	AlbumToken    TokenKind = "album"
+	SongToken     TokenKind = "song"
+	PlaylistToken TokenKind = "playlist"
+
+	consistencyPollInterval = 50 * time.Millisecond
+)
+
+var (
+	InvalidTokenErr = errors.New("invalid consistency token")
+	NotCaughtUpErr  = errors.New("projection has not caught up")
+)
+
+type (
+	TokenKind string
+
+	// ConsistencyToken identifies the version of a resource a command
+	// produced, so reads can wait until the projection has applied it.
+	ConsistencyToken struct {
+		Kind    TokenKind
+		ID      string
+		Version int
+	}
+
+	ConsistencyDatabase interface {
+		GetArtistByID(ctx context.Context, id string) (song.Artist, error)
+		GetAlbumByID(ctx context.Context, id string) (song.Album, error)
+		GetSongByID(ctx context.Context, id string) (song.Song, error)
+		GetPlaylistByID(ctx context.Context, id string) (song.Playlist, error)
+	}
+
+	WaitForProjection struct {
+		db ConsistencyDatabase
+	}
+
+	freshReadKey struct{}
+)
+
+func NewConsistencyToken(kind TokenKind, id string, version int) ConsistencyToken {
+	return ConsistencyToken{
+		Kind:    kind,
+		ID:      id,
+		Version: version,
+	}
+}
+
+func ParseConsistencyToken(value string) (ConsistencyToken, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 || parts[1] == "" {
+		return ConsistencyToken{}, fmt.Errorf("%w %q", InvalidTokenErr, value)
+	}
+
+	version, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return ConsistencyToken{}, fmt.Errorf("%w %q", InvalidTokenErr, value)
+	}
+
+	token := NewConsistencyToken(TokenKind(parts[0]), parts[1], version)
+	switch token.Kind {
+	case ArtistToken, AlbumToken, SongToken, PlaylistToken:
+		return token, nil
+	default:
+		return ConsistencyToken{}, fmt.Errorf("%w %q", InvalidTokenErr, value)
+	}
+}
+
+// WithFreshRead marks the reads made with ctx as having to see the read model
+// itself rather than a cached copy, once a consistency token was waited for.
+func WithFreshRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshReadKey{}, true)
+}
+
+func IsFreshRead(ctx context.Context) bool {
+	fresh, _ := ctx.Value(freshReadKey{}).(bool)
+	return fresh
+}
+
+func (t ConsistencyToken) String() string {
+	return fmt.Sprintf("%s:%s:%d", t.Kind, t.ID, t.Version)
+}
+
+func NewWaitForProjection(db ConsistencyDatabase) *WaitForProjection {
+	return &WaitForProjection{
+		db: db,
+	}
+}
+
+// Execute polls the read model until the resource of token reaches its
+// version, giving up with NotCaughtUpErr once timeout elapses.
+func (wp WaitForProjection) Execute(ctx context.Context, token ConsistencyToken, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(consistencyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		version, err := wp.projectedVersion(ctx, token)
+		if err != nil && !errors.Is(err, song.NotFoundErr) {
+			if ctx.Err() != nil {
+				return NotCaughtUpErr
+			}
+
+			return err
+		}
+
+		if err == nil && version >= token.Version {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return NotCaughtUpErr
+		case <-ticker.C:
+		}
+	}
+}
+
+func (wp WaitForProjection) projectedVersion(ctx context.Context, token ConsistencyToken) (int, error) {
+	switch token.Kind {
+	case ArtistToken:
+		artist, err := wp.db.GetArtistByID(ctx, token.ID)
+		return artist.Version, err
+	case AlbumToken:
+		album, err := wp.db.GetAlbumByID(ctx, token.ID)
+		return album.Version, err
+	case SongToken:
+		s, err := wp.db.GetSongByID(ctx, token.ID)
+		return s.Version, err
+	case PlaylistToken:
+		playlist, err := wp.db.GetPlaylistByID(ctx, token.ID)
+		return playlist.Version, err
+	default:
+		return 0, fmt.Errorf("%w: unknown kind %q", InvalidTokenErr, token.Kind)
+	}
+}
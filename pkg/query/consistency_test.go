@@ -0,0 +1,38 @@
+package query
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_Parse_Consistency_Token(t *testing.T) {
+	tests := []struct {
+		value string
+		want  ConsistencyToken
+		err   error
+	}{
+		{value: "album:1:3", want: NewConsistencyToken(AlbumToken, "1", 3)},
+		{value: "playlist:abc:12", want: NewConsistencyToken(PlaylistToken, "abc", 12)},
+		{value: "album:1", err: InvalidTokenErr},
+		{value: "album::3", err: InvalidTokenErr},
+		{value: "album:1:three", err: InvalidTokenErr},
+		{value: "genre:1:3", err: InvalidTokenErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseConsistencyToken(tt.value)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err: got = %v, want = %v", err, tt.err)
+			}
+
+			if got != tt.want {
+				t.Errorf("token: got = %+v, want = %+v", got, tt.want)
+			}
+
+			if tt.err == nil && got.String() != tt.value {
+				t.Errorf("String: got = %q, want = %q", got.String(), tt.value)
+			}
+		})
+	}
+}
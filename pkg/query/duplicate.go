@@ -0,0 +1,75 @@
+package query
+
+import (
+	"context"
+	"cqrs-sample/pkg/song"
+	"sort"
+)
+
+const DefaultDuplicateThreshold = 0.85
+
+type (
+	DuplicateDatabase interface {
+		WalkArtists(ctx context.Context, fn func(song.Artist) error) error
+	}
+
+	GetDuplicateArtists struct {
+		db DuplicateDatabase
+	}
+
+	candidate struct {
+		artist     song.Artist
+		normalized string
+	}
+)
+
+func NewGetDuplicateArtists(db DuplicateDatabase) *GetDuplicateArtists {
+	return &GetDuplicateArtists{
+		db: db,
+	}
+}
+
+// Execute pairs up artists whose normalized names are at least threshold
+// similar, most similar first. Names are compared pairwise, so this is meant
+// for periodic review rather than the request path of listeners.
+func (gd GetDuplicateArtists) Execute(ctx context.Context, threshold float64) ([]DuplicateArtistsResponse, error) {
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultDuplicateThreshold
+	}
+
+	var candidates []candidate
+	err := gd.db.WalkArtists(ctx, func(artist song.Artist) error {
+		candidates = append(candidates, candidate{
+			artist:     artist,
+			normalized: song.NormalizeArtistName(artist.Name),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]DuplicateArtistsResponse, 0)
+	for i := range candidates {
+		for j := i + 1; j < len(candidates); j++ {
+			similarity := song.NameSimilarity(candidates[i].normalized, candidates[j].normalized)
+			if similarity < threshold {
+				continue
+			}
+
+			output = append(output, DuplicateArtistsResponse{
+				Artists: []ArtistResponse{
+					NewArtistResponseFromDomain(candidates[i].artist),
+					NewArtistResponseFromDomain(candidates[j].artist),
+				},
+				Similarity: similarity,
+			})
+		}
+	}
+
+	sort.SliceStable(output, func(i, j int) bool {
+		return output[i].Similarity > output[j].Similarity
+	})
+
+	return output, nil
+}
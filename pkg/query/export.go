@@ -0,0 +1,193 @@
+package query
+
+import (
+	"bufio"
+	"context"
+	"cqrs-sample/pkg/song"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	CSVFormat   = "csv"
+	JSONLFormat = "jsonl"
+)
+
+var (
+	UnsupportedFormatErr = errors.New("unsupported export format")
+
+	catalogColumns = []string{
+		"type", "id", "artist_id", "artist", "gender", "genres", "album_id", "album", "release_year",
+		"status", "title", "track_number", "disc_number", "duration_ms", "isrc", "explicit", "composers",
+		"plays", "likes",
+	}
+)
+
+type (
+	CatalogDatabase interface {
+		WalkArtists(ctx context.Context, fn func(song.Artist) error) error
+		WalkAlbums(ctx context.Context, fn func(song.Album) error) error
+		WalkSongs(ctx context.Context, fn func(song.Song) error) error
+	}
+
+	ExportCatalog struct {
+		db CatalogDatabase
+	}
+
+	catalogWriter interface {
+		Write(record CatalogRecord) error
+		Flush() error
+	}
+
+	jsonlWriter struct {
+		buf *bufio.Writer
+		enc *json.Encoder
+	}
+
+	csvWriter struct {
+		w      *csv.Writer
+		header bool
+	}
+)
+
+func NewExportCatalog(db CatalogDatabase) *ExportCatalog {
+	return &ExportCatalog{
+		db: db,
+	}
+}
+
+func ValidateExportFormat(format string) error {
+	switch format {
+	case CSVFormat, JSONLFormat:
+		return nil
+	default:
+		return fmt.Errorf("%w %q", UnsupportedFormatErr, format)
+	}
+}
+
+// Execute streams every artist and every released album and song of the read
+// model to w, in that order so the output can be fed back to the catalog import.
+func (ec ExportCatalog) Execute(ctx context.Context, format string, w io.Writer) error {
+	if err := ValidateExportFormat(format); err != nil {
+		return err
+	}
+
+	var cw catalogWriter
+	if format == CSVFormat {
+		cw = newCSVWriter(w)
+	} else {
+		cw = newJSONLWriter(w)
+	}
+
+	err := ec.db.WalkArtists(ctx, func(artist song.Artist) error {
+		return cw.Write(NewCatalogRecordFromArtist(artist))
+	})
+	if err != nil {
+		return err
+	}
+
+	err = ec.db.WalkAlbums(ctx, func(album song.Album) error {
+		return cw.Write(NewCatalogRecordFromAlbum(album))
+	})
+	if err != nil {
+		return err
+	}
+
+	err = ec.db.WalkSongs(ctx, func(s song.Song) error {
+		return cw.Write(NewCatalogRecordFromSong(s))
+	})
+	if err != nil {
+		return err
+	}
+
+	return cw.Flush()
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	buf := bufio.NewWriter(w)
+	return &jsonlWriter{
+		buf: buf,
+		enc: json.NewEncoder(buf),
+	}
+}
+
+func (j *jsonlWriter) Write(record CatalogRecord) error {
+	return j.enc.Encode(record)
+}
+
+func (j *jsonlWriter) Flush() error {
+	return j.buf.Flush()
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{
+		w: csv.NewWriter(w),
+	}
+}
+
+func (c *csvWriter) Write(record CatalogRecord) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	return c.w.Write([]string{
+		record.Type,
+		record.ID,
+		record.ArtistID,
+		record.Artist,
+		record.Gender,
+		strings.Join(record.Genres, "|"),
+		record.AlbumID,
+		record.Album,
+		formatInt(int64(record.ReleaseYear)),
+		record.Status,
+		record.Title,
+		formatInt(int64(record.TrackNumber)),
+		formatInt(int64(record.DiscNumber)),
+		formatInt(record.DurationMs),
+		record.ISRC,
+		formatBool(record.Explicit),
+		strings.Join(record.Composers, "|"),
+		formatInt(int64(record.Plays)),
+		formatInt(int64(record.Likes)),
+	})
+}
+
+func (c *csvWriter) Flush() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+
+	c.header = true
+	return c.w.Write(catalogColumns)
+}
+
+func formatBool(v bool) string {
+	if !v {
+		return ""
+	}
+
+	return strconv.FormatBool(v)
+}
+
+func formatInt(v int64) string {
+	if v == 0 {
+		return ""
+	}
+
+	return strconv.FormatInt(v, 10)
+}
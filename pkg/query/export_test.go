@@ -0,0 +1,193 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"cqrs-sample/pkg/command"
+	"cqrs-sample/pkg/song"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeCatalogDatabase struct {
+	artists []song.Artist
+	albums  []song.Album
+	songs   []song.Song
+}
+
+func (f fakeCatalogDatabase) WalkArtists(_ context.Context, fn func(song.Artist) error) error {
+	for _, artist := range f.artists {
+		if err := fn(artist); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f fakeCatalogDatabase) WalkAlbums(_ context.Context, fn func(song.Album) error) error {
+	for _, album := range f.albums {
+		if err := fn(album); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f fakeCatalogDatabase) WalkSongs(_ context.Context, fn func(song.Song) error) error {
+	for _, s := range f.songs {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newFakeCatalog() fakeCatalogDatabase {
+	artist := song.Artist{ID: "ar1", Name: "Some, Artist", Gender: "Some Gender", Genres: []song.Genre{"rock", "pop"}}
+	album := song.Album{ID: "al1", Title: "Some Album", Artist: artist, ReleaseYear: 2024, Status: song.PublishedStatus}
+	track := song.Song{
+		ID:          "s1",
+		Title:       `Some "Song"`,
+		TrackNumber: 1,
+		DiscNumber:  1,
+		Duration:    3 * time.Minute,
+		ISRC:        "USRC17607839",
+		Explicit:    true,
+		Composers:   []string{"First", "Second"},
+		Album:       album,
+		Artist:      artist,
+		Plays:       7,
+	}
+
+	return fakeCatalogDatabase{
+		artists: []song.Artist{artist},
+		albums:  []song.Album{album},
+		songs:   []song.Song{track},
+	}
+}
+
+func export(t *testing.T, format string) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := NewExportCatalog(newFakeCatalog()).Execute(context.Background(), format, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return out.String()
+}
+
+func Test_Export_Catalog_As_CSV(t *testing.T) {
+	// Act
+	got := export(t, CSVFormat)
+
+	// Assert
+	want := strings.Join([]string{
+		strings.Join(catalogColumns, ","),
+		`artist,ar1,,"Some, Artist",Some Gender,rock|pop,,,,,,,,,,,,,`,
+		`album,al1,ar1,"Some, Artist",,,,Some Album,2024,published,,,,,,,,,`,
+		`track,s1,ar1,"Some, Artist",,,al1,Some Album,,published,"Some ""Song""",1,1,180000,USRC17607839,true,First|Second,7,`,
+		``,
+	}, "\n")
+	if got != want {
+		t.Errorf("csv:\ngot  = %q\nwant = %q", got, want)
+	}
+}
+
+func Test_Export_Catalog_As_CSV_Writes_Header_When_Empty(t *testing.T) {
+	// Arrange
+	var out bytes.Buffer
+
+	// Act
+	err := NewExportCatalog(fakeCatalogDatabase{}).Execute(context.Background(), CSVFormat, &out)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("err: got = %v, want = nil", err)
+	}
+
+	if want := strings.Join(catalogColumns, ",") + "\n"; out.String() != want {
+		t.Errorf("csv: got = %q, want = %q", out.String(), want)
+	}
+}
+
+func Test_Export_Catalog_As_JSONL(t *testing.T) {
+	// Act
+	got := export(t, JSONLFormat)
+
+	// Assert
+	want := strings.Join([]string{
+		`{"type":"artist","id":"ar1","artist":"Some, Artist","gender":"Some Gender","genres":["rock","pop"]}`,
+		`{"type":"album","id":"al1","artist_id":"ar1","artist":"Some, Artist","album":"Some Album","release_year":2024,"status":"published"}`,
+		`{"type":"track","id":"s1","artist_id":"ar1","artist":"Some, Artist","album_id":"al1","album":"Some Album","status":"published",` +
+			`"title":"Some \"Song\"","track_number":1,"disc_number":1,"duration_ms":180000,"isrc":"USRC17607839","explicit":true,` +
+			`"composers":["First","Second"],"plays":7}`,
+		``,
+	}, "\n")
+	if got != want {
+		t.Errorf("jsonl:\ngot  = %s\nwant = %s", got, want)
+	}
+}
+
+func Test_Export_Rejects_Unsupported_Format(t *testing.T) {
+	// Act
+	err := NewExportCatalog(newFakeCatalog()).Execute(context.Background(), "xml", &bytes.Buffer{})
+
+	// Assert
+	if !errors.Is(err, UnsupportedFormatErr) {
+		t.Errorf("err: got = %v, want = %v", err, UnsupportedFormatErr)
+	}
+}
+
+func Test_Export_Can_Be_Imported(t *testing.T) {
+	want := []song.ImportRecord{
+		{Type: song.ArtistRecord, Artist: "Some, Artist", Gender: "Some Gender", Genres: []string{"rock", "pop"}},
+		{Type: song.AlbumRecord, Artist: "Some, Artist", Album: "Some Album", ReleaseYear: 2024},
+		{
+			Type:        song.TrackRecord,
+			Artist:      "Some, Artist",
+			Album:       "Some Album",
+			Title:       `Some "Song"`,
+			TrackNumber: 1,
+			DiscNumber:  1,
+			Duration:    3 * time.Minute,
+			ISRC:        "USRC17607839",
+			Explicit:    true,
+			Composers:   []string{"First", "Second"},
+		},
+	}
+
+	for _, format := range []string{CSVFormat, JSONLFormat} {
+		t.Run(format, func(t *testing.T) {
+			// Arrange
+			exported := export(t, format)
+
+			// Act
+			rows, err := command.ParseImport(format, strings.NewReader(exported))
+
+			// Assert
+			if err != nil {
+				t.Fatalf("err: got = %v, want = nil", err)
+			}
+
+			var got []song.ImportRecord
+			for _, row := range rows {
+				if row.Status != song.RowPending {
+					t.Errorf("line %d: got = %s %s, want = pending", row.Line, row.Status, row.Error)
+				}
+
+				got = append(got, row.Record)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("records:\ngot  = %+v\nwant = %+v", got, want)
+			}
+		})
+	}
+}
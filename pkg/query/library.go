@@ -3,9 +3,21 @@ package query
 import (
 	"context"
 	"cqrs-sample/pkg/song"
+	"errors"
+	"fmt"
+)
+
+var (
+	MovedErr = errors.New("moved")
 )
 
 type (
+	// ArtistMovedErr reports an artist merged into another one, whose ID
+	// requests for the old artist should be redirected to.
+	ArtistMovedErr struct {
+		ArtistID string
+	}
+
 	AlbumDatabase interface {
 		GetAlbumByID(ctx context.Context, id string) (song.Album, error)
 	}
@@ -13,12 +25,29 @@ type (
 	ArtistDatabase interface {
 		GetArtistByID(ctx context.Context, id string) (song.Artist, error)
 		GetAlbumsByArtistID(ctx context.Context, artistID string) ([]song.Album, error)
+		GetSongsFeaturingArtist(ctx context.Context, artistID string) ([]song.Song, error)
+		GetArtistRedirect(ctx context.Context, id string) (string, error)
 	}
 
 	SongDatabase interface {
 		GetSongByID(ctx context.Context, id string) (song.Song, error)
 	}
 
+	ListenerDatabase interface {
+		GetListeningHistory(ctx context.Context, listenerID string, offset, limit int) ([]song.Listening, int64, error)
+		GetFeed(ctx context.Context, listenerID string, offset, limit int) ([]song.Release, int64, error)
+		GetLikes(ctx context.Context, listenerID string, offset, limit int) ([]song.Like, int64, error)
+	}
+
+	GenreDatabase interface {
+		GetAlbumsByGenre(ctx context.Context, genre song.Genre, offset, limit int) ([]song.Album, int64, error)
+		GetArtistsByGenre(ctx context.Context, genre song.Genre, offset, limit int) ([]song.Artist, int64, error)
+	}
+
+	PlaylistDatabase interface {
+		GetPlaylistByID(ctx context.Context, id string) (song.Playlist, error)
+	}
+
 	GetAlbum struct {
 		db AlbumDatabase
 	}
@@ -34,6 +63,30 @@ type (
 	GetSong struct {
 		db SongDatabase
 	}
+
+	GetListeningHistory struct {
+		db ListenerDatabase
+	}
+
+	GetPlaylist struct {
+		db PlaylistDatabase
+	}
+
+	GetFeed struct {
+		db ListenerDatabase
+	}
+
+	GetLikes struct {
+		db ListenerDatabase
+	}
+
+	GetAlbumsByGenre struct {
+		db GenreDatabase
+	}
+
+	GetArtistsByGenre struct {
+		db GenreDatabase
+	}
 )
 
 func NewGetAlbum(db AlbumDatabase) *GetAlbum {
@@ -60,12 +113,52 @@ func NewGetSong(db SongDatabase) *GetSong {
 	}
 }
 
+func NewGetListeningHistory(db ListenerDatabase) *GetListeningHistory {
+	return &GetListeningHistory{
+		db: db,
+	}
+}
+
+func NewGetPlaylist(db PlaylistDatabase) *GetPlaylist {
+	return &GetPlaylist{
+		db: db,
+	}
+}
+
+func NewGetFeed(db ListenerDatabase) *GetFeed {
+	return &GetFeed{
+		db: db,
+	}
+}
+
+func NewGetLikes(db ListenerDatabase) *GetLikes {
+	return &GetLikes{
+		db: db,
+	}
+}
+
+func NewGetAlbumsByGenre(db GenreDatabase) *GetAlbumsByGenre {
+	return &GetAlbumsByGenre{
+		db: db,
+	}
+}
+
+func NewGetArtistsByGenre(db GenreDatabase) *GetArtistsByGenre {
+	return &GetArtistsByGenre{
+		db: db,
+	}
+}
+
 func (ga GetAlbum) Execute(ctx context.Context, id string) (AlbumResponse, error) {
 	album, err := ga.db.GetAlbumByID(ctx, id)
 	if err != nil {
 		return AlbumResponse{}, err
 	}
 
+	if !album.IsReleased() {
+		return AlbumResponse{}, song.NotFoundErr
+	}
+
 	return NewAlbumResponseFromDomain(album), nil
 }
 
@@ -83,13 +176,33 @@ func (ga GetAlbumsByArtist) Execute(ctx context.Context, artistID string) ([]Alb
 	return output, nil
 }
 
-func (ga GetArtist) Execute(ctx context.Context, id string) (ArtistResponse, error) {
+func (ga GetArtist) Execute(ctx context.Context, id string) (ArtistDetailsResponse, error) {
 	artist, err := ga.db.GetArtistByID(ctx, id)
+	if errors.Is(err, song.NotFoundErr) {
+		return ArtistDetailsResponse{}, ga.redirect(ctx, id, err)
+	}
+	if err != nil {
+		return ArtistDetailsResponse{}, err
+	}
+
+	featuredOn, err := ga.db.GetSongsFeaturingArtist(ctx, id)
 	if err != nil {
-		return ArtistResponse{}, err
+		return ArtistDetailsResponse{}, err
 	}
 
-	return NewArtistResponseFromDomain(artist), nil
+	return NewArtistDetailsResponseFromDomain(artist, featuredOn), nil
+}
+
+func (ga GetArtist) redirect(ctx context.Context, id string, notFound error) error {
+	targetID, err := ga.db.GetArtistRedirect(ctx, id)
+	if errors.Is(err, song.NotFoundErr) {
+		return notFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return ArtistMovedErr{ArtistID: targetID}
 }
 
 func (gs GetSong) Execute(ctx context.Context, id string) (SongResponse, error) {
@@ -98,5 +211,96 @@ func (gs GetSong) Execute(ctx context.Context, id string) (SongResponse, error)
 		return SongResponse{}, err
 	}
 
+	if !s.Album.IsReleased() {
+		return SongResponse{}, song.NotFoundErr
+	}
+
 	return NewSongResponseFromDomain(s), err
 }
+
+func (gh GetListeningHistory) Execute(ctx context.Context, listenerID string, p Pagination) (PageResponse[ListeningResponse], error) {
+	history, total, err := gh.db.GetListeningHistory(ctx, listenerID, p.Offset(), p.Size)
+	if err != nil {
+		return PageResponse[ListeningResponse]{}, err
+	}
+
+	output := make([]ListeningResponse, len(history), len(history))
+	for i, l := range history {
+		output[i] = NewListeningResponseFromDomain(l)
+	}
+
+	return NewPageResponse(output, p, total), nil
+}
+
+func (gp GetPlaylist) Execute(ctx context.Context, id string) (PlaylistResponse, error) {
+	playlist, err := gp.db.GetPlaylistByID(ctx, id)
+	if err != nil {
+		return PlaylistResponse{}, err
+	}
+
+	return NewPlaylistResponseFromDomain(playlist), nil
+}
+
+func (gf GetFeed) Execute(ctx context.Context, listenerID string, p Pagination) (PageResponse[ReleaseResponse], error) {
+	releases, total, err := gf.db.GetFeed(ctx, listenerID, p.Offset(), p.Size)
+	if err != nil {
+		return PageResponse[ReleaseResponse]{}, err
+	}
+
+	output := make([]ReleaseResponse, len(releases), len(releases))
+	for i, r := range releases {
+		output[i] = NewReleaseResponseFromDomain(r)
+	}
+
+	return NewPageResponse(output, p, total), nil
+}
+
+func (gl GetLikes) Execute(ctx context.Context, listenerID string, p Pagination) (PageResponse[LikeResponse], error) {
+	likes, total, err := gl.db.GetLikes(ctx, listenerID, p.Offset(), p.Size)
+	if err != nil {
+		return PageResponse[LikeResponse]{}, err
+	}
+
+	output := make([]LikeResponse, len(likes), len(likes))
+	for i, l := range likes {
+		output[i] = NewLikeResponseFromDomain(l)
+	}
+
+	return NewPageResponse(output, p, total), nil
+}
+
+func (ga GetAlbumsByGenre) Execute(ctx context.Context, genre string, p Pagination) (PageResponse[AlbumResponse], error) {
+	albums, total, err := ga.db.GetAlbumsByGenre(ctx, song.NewGenre(genre), p.Offset(), p.Size)
+	if err != nil {
+		return PageResponse[AlbumResponse]{}, err
+	}
+
+	output := make([]AlbumResponse, len(albums), len(albums))
+	for i, album := range albums {
+		output[i] = NewAlbumResponseFromDomain(album)
+	}
+
+	return NewPageResponse(output, p, total), nil
+}
+
+func (ga GetArtistsByGenre) Execute(ctx context.Context, genre string, p Pagination) (PageResponse[ArtistResponse], error) {
+	artists, total, err := ga.db.GetArtistsByGenre(ctx, song.NewGenre(genre), p.Offset(), p.Size)
+	if err != nil {
+		return PageResponse[ArtistResponse]{}, err
+	}
+
+	output := make([]ArtistResponse, len(artists), len(artists))
+	for i, artist := range artists {
+		output[i] = NewArtistResponseFromDomain(artist)
+	}
+
+	return NewPageResponse(output, p, total), nil
+}
+
+func (e ArtistMovedErr) Error() string {
+	return fmt.Sprintf("%s: artist merged into %s", MovedErr, e.ArtistID)
+}
+
+func (e ArtistMovedErr) Unwrap() error {
+	return MovedErr
+}
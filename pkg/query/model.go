@@ -1,8 +1,110 @@
 package query
 
-import "cqrs-sample/pkg/song"
+import (
+	"cqrs-sample/pkg/song"
+	"time"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
 
 type (
+	Pagination struct {
+		Page int
+		Size int
+	}
+
+	PageResponse[T any] struct {
+		Items []T   `json:"items"`
+		Page  int   `json:"page"`
+		Size  int   `json:"size"`
+		Total int64 `json:"total"`
+	}
+
+	PlaylistResponse struct {
+		ID              string                   `json:"id"`
+		Name            string                   `json:"name"`
+		ListenerID      string                   `json:"listener_id"`
+		Songs           []SongInPlaylistResponse `json:"songs"`
+		TotalDurationMs int64                    `json:"total_duration_ms"`
+	}
+
+	SongInPlaylistResponse struct {
+		Position   int                 `json:"position"`
+		ID         string              `json:"id"`
+		Title      string              `json:"title"`
+		DurationMs int64               `json:"duration_ms"`
+		Album      AlbumInSongResponse `json:"album"`
+		Artist     ArtistResponse      `json:"artist"`
+	}
+
+	LikeResponse struct {
+		Song    ListenedSongResponse `json:"song"`
+		LikedAt time.Time            `json:"liked_at"`
+	}
+
+	ReleaseResponse struct {
+		Album       AlbumInSongResponse `json:"album"`
+		Artist      ArtistResponse      `json:"artist"`
+		PublishedAt time.Time           `json:"published_at"`
+	}
+
+	ListeningResponse struct {
+		ID       string               `json:"id"`
+		Song     ListenedSongResponse `json:"song"`
+		PlayedAt time.Time            `json:"played_at"`
+	}
+
+	ListenedSongResponse struct {
+		ID          string              `json:"id"`
+		TrackNumber int                 `json:"track_number"`
+		Title       string              `json:"title"`
+		Album       AlbumInSongResponse `json:"album"`
+		Artist      ArtistResponse      `json:"artist"`
+	}
+
+	DuplicateArtistsResponse struct {
+		Artists    []ArtistResponse `json:"artists"`
+		Similarity float64          `json:"similarity"`
+	}
+
+	ProjectionResponse struct {
+		Name            string     `json:"name"`
+		LastEventAt     *time.Time `json:"last_event_at,omitempty"`
+		LastProcessedAt *time.Time `json:"last_processed_at,omitempty"`
+		Processed       int64      `json:"processed"`
+		Pending         int        `json:"pending"`
+		SampledAt       *time.Time `json:"sampled_at,omitempty"`
+		LagMs           int64      `json:"lag_ms"`
+		Stuck           bool       `json:"stuck"`
+		LastError       string     `json:"last_error,omitempty"`
+		LastErrorAt     *time.Time `json:"last_error_at,omitempty"`
+	}
+
+	CatalogRecord struct {
+		Type        string   `json:"type"`
+		ID          string   `json:"id"`
+		ArtistID    string   `json:"artist_id,omitempty"`
+		Artist      string   `json:"artist"`
+		Gender      string   `json:"gender,omitempty"`
+		Genres      []string `json:"genres,omitempty"`
+		AlbumID     string   `json:"album_id,omitempty"`
+		Album       string   `json:"album,omitempty"`
+		ReleaseYear int      `json:"release_year,omitempty"`
+		Status      string   `json:"status,omitempty"`
+		Title       string   `json:"title,omitempty"`
+		TrackNumber int      `json:"track_number,omitempty"`
+		DiscNumber  int      `json:"disc_number,omitempty"`
+		DurationMs  int64    `json:"duration_ms,omitempty"`
+		ISRC        string   `json:"isrc,omitempty"`
+		Explicit    bool     `json:"explicit,omitempty"`
+		Composers   []string `json:"composers,omitempty"`
+		Plays       int      `json:"plays,omitempty"`
+		Likes       int      `json:"likes,omitempty"`
+	}
+
 	AlbumInSongResponse struct {
 		ID          string `json:"id"`
 		Title       string `json:"title"`
@@ -10,35 +112,110 @@ type (
 	}
 
 	AlbumResponse struct {
-		ID          string                `json:"id"`
-		Title       string                `json:"title"`
-		Artist      ArtistResponse        `json:"artist"`
-		ReleaseYear int                   `json:"release_year"`
-		Songs       []SongInAlbumResponse `json:"songs"`
+		ID              string                `json:"id"`
+		Title           string                `json:"title"`
+		Artist          ArtistResponse        `json:"artist"`
+		ReleaseYear     int                   `json:"release_year"`
+		ReleaseDate     *time.Time            `json:"release_date,omitempty"`
+		Genres          []string              `json:"genres,omitempty"`
+		TotalDurationMs int64                 `json:"total_duration_ms"`
+		Rating          RatingResponse        `json:"rating"`
+		Songs           []SongInAlbumResponse `json:"songs"`
+		Version         int                   `json:"version"`
+		UpdatedAt       time.Time             `json:"-"`
+	}
+
+	RatingResponse struct {
+		Average float64 `json:"average"`
+		Count   int     `json:"count"`
 	}
 
 	ArtistResponse struct {
-		ID     string `json:"id"`
-		Name   string `json:"name"`
-		Gender string `json:"gender"`
+		ID     string   `json:"id"`
+		Name   string   `json:"name"`
+		Gender string   `json:"gender"`
+		Genres []string `json:"genres,omitempty"`
+	}
+
+	ArtistDetailsResponse struct {
+		ArtistResponse
+		FeaturedOn []FeaturedSongResponse `json:"featured_on"`
+		Version    int                    `json:"version"`
+		UpdatedAt  time.Time              `json:"-"`
+	}
+
+	FeaturedSongResponse struct {
+		ID     string              `json:"id"`
+		Title  string              `json:"title"`
+		Role   string              `json:"role"`
+		Album  AlbumInSongResponse `json:"album"`
+		Artist ArtistResponse      `json:"artist"`
+	}
+
+	FeaturingResponse struct {
+		Artist ArtistResponse `json:"artist"`
+		Role   string         `json:"role"`
 	}
 
 	SongResponse struct {
 		ID          string              `json:"id"`
 		TrackNumber int                 `json:"track_number"`
+		DiscNumber  int                 `json:"disc_number"`
 		Title       string              `json:"title"`
+		DurationMs  int64               `json:"duration_ms"`
+		ISRC        string              `json:"isrc,omitempty"`
+		Explicit    bool                `json:"explicit"`
+		Composers   []string            `json:"composers,omitempty"`
 		Plays       int                 `json:"plays"`
+		Likes       int                 `json:"likes"`
 		Album       AlbumInSongResponse `json:"album"`
 		Artist      ArtistResponse      `json:"artist"`
+		Featuring   []FeaturingResponse `json:"featuring,omitempty"`
+		Version     int                 `json:"version"`
+		UpdatedAt   time.Time           `json:"-"`
 	}
 
 	SongInAlbumResponse struct {
-		ID          string `json:"id"`
-		TrackNumber int    `json:"track_number"`
-		Title       string `json:"title"`
+		ID          string              `json:"id"`
+		TrackNumber int                 `json:"track_number"`
+		DiscNumber  int                 `json:"disc_number"`
+		Title       string              `json:"title"`
+		DurationMs  int64               `json:"duration_ms"`
+		Explicit    bool                `json:"explicit"`
+		Featuring   []FeaturingResponse `json:"featuring,omitempty"`
 	}
 )
 
+func NewPagination(page, size int) Pagination {
+	if page < 1 {
+		page = 1
+	}
+
+	if size < 1 {
+		size = defaultPageSize
+	} else if size > maxPageSize {
+		size = maxPageSize
+	}
+
+	return Pagination{
+		Page: page,
+		Size: size,
+	}
+}
+
+func (p Pagination) Offset() int {
+	return (p.Page - 1) * p.Size
+}
+
+func NewPageResponse[T any](items []T, p Pagination, total int64) PageResponse[T] {
+	return PageResponse[T]{
+		Items: items,
+		Page:  p.Page,
+		Size:  p.Size,
+		Total: total,
+	}
+}
+
 func NewAlbumInSongResponseFromDomain(album song.Album) AlbumInSongResponse {
 	return AlbumInSongResponse{
 		ID:          album.ID,
@@ -51,7 +228,11 @@ func NewSongInAlbumResponseFromDomain(s song.Song) SongInAlbumResponse {
 	return SongInAlbumResponse{
 		ID:          s.ID,
 		TrackNumber: s.TrackNumber,
+		DiscNumber:  s.DiscNumber,
 		Title:       s.Title,
+		DurationMs:  s.Duration.Milliseconds(),
+		Explicit:    s.Explicit,
+		Featuring:   newFeaturingResponseFromDomain(s.Featuring),
 	}
 }
 
@@ -62,11 +243,20 @@ func NewAlbumResponseFromDomain(album song.Album) AlbumResponse {
 	}
 
 	return AlbumResponse{
-		ID:          album.ID,
-		Title:       album.Title,
-		Artist:      NewArtistResponseFromDomain(album.Artist),
-		ReleaseYear: album.ReleaseYear,
-		Songs:       songs,
+		ID:              album.ID,
+		Title:           album.Title,
+		Artist:          NewArtistResponseFromDomain(album.Artist),
+		ReleaseYear:     album.ReleaseYear,
+		ReleaseDate:     releaseDate(album),
+		Genres:          song.GenreNames(album.Genres),
+		TotalDurationMs: album.Duration.Milliseconds(),
+		Rating: RatingResponse{
+			Average: album.Rating.Average,
+			Count:   album.Rating.Count,
+		},
+		Songs:     songs,
+		Version:   album.Version,
+		UpdatedAt: album.UpdatedAt,
 	}
 }
 
@@ -75,6 +265,7 @@ func NewArtistResponseFromDomain(artist song.Artist) ArtistResponse {
 		ID:     artist.ID,
 		Name:   artist.Name,
 		Gender: string(artist.Gender),
+		Genres: song.GenreNames(artist.Genres),
 	}
 }
 
@@ -82,9 +273,186 @@ func NewSongResponseFromDomain(s song.Song) SongResponse {
 	return SongResponse{
 		ID:          s.ID,
 		TrackNumber: s.TrackNumber,
+		DiscNumber:  s.DiscNumber,
 		Title:       s.Title,
+		DurationMs:  s.Duration.Milliseconds(),
+		ISRC:        s.ISRC,
+		Explicit:    s.Explicit,
+		Composers:   s.Composers,
 		Plays:       s.Plays,
+		Likes:       s.Likes,
 		Album:       NewAlbumInSongResponseFromDomain(s.Album),
 		Artist:      NewArtistResponseFromDomain(s.Artist),
+		Featuring:   newFeaturingResponseFromDomain(s.Featuring),
+		Version:     s.Version,
+		UpdatedAt:   s.UpdatedAt,
 	}
 }
+
+func NewListeningResponseFromDomain(l song.Listening) ListeningResponse {
+	return ListeningResponse{
+		ID:       l.ID,
+		Song:     NewListenedSongResponseFromDomain(l.Song),
+		PlayedAt: l.PlayedAt,
+	}
+}
+
+func NewLikeResponseFromDomain(l song.Like) LikeResponse {
+	return LikeResponse{
+		Song:    NewListenedSongResponseFromDomain(l.Song),
+		LikedAt: l.LikedAt,
+	}
+}
+
+func NewListenedSongResponseFromDomain(s song.Song) ListenedSongResponse {
+	return ListenedSongResponse{
+		ID:          s.ID,
+		TrackNumber: s.TrackNumber,
+		Title:       s.Title,
+		Album:       NewAlbumInSongResponseFromDomain(s.Album),
+		Artist:      NewArtistResponseFromDomain(s.Artist),
+	}
+}
+
+func NewArtistDetailsResponseFromDomain(artist song.Artist, featuredOn []song.Song) ArtistDetailsResponse {
+	songs := make([]FeaturedSongResponse, 0, len(featuredOn))
+	for _, s := range featuredOn {
+		for _, f := range s.Featuring {
+			if f.Artist.ID != artist.ID {
+				continue
+			}
+
+			songs = append(songs, FeaturedSongResponse{
+				ID:     s.ID,
+				Title:  s.Title,
+				Role:   string(f.Role),
+				Album:  NewAlbumInSongResponseFromDomain(s.Album),
+				Artist: NewArtistResponseFromDomain(s.Artist),
+			})
+		}
+	}
+
+	return ArtistDetailsResponse{
+		ArtistResponse: NewArtistResponseFromDomain(artist),
+		FeaturedOn:     songs,
+		Version:        artist.Version,
+		UpdatedAt:      artist.UpdatedAt,
+	}
+}
+
+func NewPlaylistResponseFromDomain(p song.Playlist) PlaylistResponse {
+	songs := make([]SongInPlaylistResponse, len(p.Songs), len(p.Songs))
+	for i, s := range p.Songs {
+		songs[i] = SongInPlaylistResponse{
+			Position:   i + 1,
+			ID:         s.ID,
+			Title:      s.Title,
+			DurationMs: s.Duration.Milliseconds(),
+			Album:      NewAlbumInSongResponseFromDomain(s.Album),
+			Artist:     NewArtistResponseFromDomain(s.Artist),
+		}
+	}
+
+	return PlaylistResponse{
+		ID:              p.ID,
+		Name:            p.Name,
+		ListenerID:      p.ListenerID,
+		Songs:           songs,
+		TotalDurationMs: p.Duration().Milliseconds(),
+	}
+}
+
+func NewReleaseResponseFromDomain(r song.Release) ReleaseResponse {
+	return ReleaseResponse{
+		Album:       NewAlbumInSongResponseFromDomain(r.Album),
+		Artist:      NewArtistResponseFromDomain(r.Album.Artist),
+		PublishedAt: r.PublishedAt,
+	}
+}
+
+func newFeaturingResponseFromDomain(featuring []song.Featuring) []FeaturingResponse {
+	var output []FeaturingResponse
+	for _, f := range featuring {
+		output = append(output, FeaturingResponse{
+			Artist: NewArtistResponseFromDomain(f.Artist),
+			Role:   string(f.Role),
+		})
+	}
+
+	return output
+}
+
+func NewCatalogRecordFromArtist(artist song.Artist) CatalogRecord {
+	return CatalogRecord{
+		Type:   string(song.ArtistRecord),
+		ID:     artist.ID,
+		Artist: artist.Name,
+		Gender: string(artist.Gender),
+		Genres: song.GenreNames(artist.Genres),
+	}
+}
+
+func NewCatalogRecordFromAlbum(album song.Album) CatalogRecord {
+	return CatalogRecord{
+		Type:        string(song.AlbumRecord),
+		ID:          album.ID,
+		ArtistID:    album.Artist.ID,
+		Artist:      album.Artist.Name,
+		Genres:      song.GenreNames(album.Genres),
+		Album:       album.Title,
+		ReleaseYear: album.ReleaseYear,
+		Status:      string(album.Status),
+	}
+}
+
+func NewCatalogRecordFromSong(s song.Song) CatalogRecord {
+	return CatalogRecord{
+		Type:        string(song.TrackRecord),
+		ID:          s.ID,
+		ArtistID:    s.Artist.ID,
+		Artist:      s.Artist.Name,
+		AlbumID:     s.Album.ID,
+		Album:       s.Album.Title,
+		Status:      string(s.Album.Status),
+		Title:       s.Title,
+		TrackNumber: s.TrackNumber,
+		DiscNumber:  s.DiscNumber,
+		DurationMs:  s.Duration.Milliseconds(),
+		ISRC:        s.ISRC,
+		Explicit:    s.Explicit,
+		Composers:   s.Composers,
+		Plays:       s.Plays,
+		Likes:       s.Likes,
+	}
+}
+
+func releaseDate(album song.Album) *time.Time {
+	if album.ReleaseDate.IsZero() {
+		return nil
+	}
+
+	return &album.ReleaseDate
+}
+
+func NewProjectionResponseFromDomain(c song.Checkpoint, now time.Time, stuckAfter time.Duration) ProjectionResponse {
+	return ProjectionResponse{
+		Name:            c.Projection,
+		LastEventAt:     optionalTime(c.LastEventAt),
+		LastProcessedAt: optionalTime(c.LastProcessedAt),
+		Processed:       c.Processed,
+		Pending:         c.Pending,
+		SampledAt:       optionalTime(c.SampledAt),
+		LagMs:           c.Lag(now).Milliseconds(),
+		Stuck:           c.IsStuck(now, stuckAfter),
+		LastError:       c.LastError,
+		LastErrorAt:     optionalTime(c.LastErrorAt),
+	}
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,46 @@
+package query
+
+import (
+	"context"
+	"cqrs-sample/pkg/song"
+	"time"
+)
+
+const DefaultStuckAfter = 5 * time.Minute
+
+type (
+	ProjectionDatabase interface {
+		GetCheckpoints(ctx context.Context) ([]song.Checkpoint, error)
+	}
+
+	GetProjections struct {
+		db         ProjectionDatabase
+		stuckAfter time.Duration
+	}
+)
+
+func NewGetProjections(db ProjectionDatabase, stuckAfter time.Duration) *GetProjections {
+	if stuckAfter <= 0 {
+		stuckAfter = DefaultStuckAfter
+	}
+
+	return &GetProjections{
+		db:         db,
+		stuckAfter: stuckAfter,
+	}
+}
+
+func (gp GetProjections) Execute(ctx context.Context) ([]ProjectionResponse, error) {
+	checkpoints, err := gp.db.GetCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	output := make([]ProjectionResponse, len(checkpoints), len(checkpoints))
+	for i, c := range checkpoints {
+		output[i] = NewProjectionResponseFromDomain(c, now, gp.stuckAfter)
+	}
+
+	return output, nil
+}
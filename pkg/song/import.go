@@ -0,0 +1,96 @@
+package song
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	ArtistRecord RecordType = "artist"
+	AlbumRecord  RecordType = "album"
+	TrackRecord  RecordType = "track"
+
+	ImportPending   ImportStatus = "pending"
+	ImportRunning   ImportStatus = "running"
+	ImportCompleted ImportStatus = "completed"
+
+	RowPending   RowStatus = "pending"
+	RowSucceeded RowStatus = "succeeded"
+	RowFailed    RowStatus = "failed"
+)
+
+type (
+	RecordType   string
+	ImportStatus string
+	RowStatus    string
+
+	ImportRecord struct {
+		Type        RecordType
+		Artist      string
+		Gender      string
+		Genres      []string
+		Album       string
+		ReleaseYear int
+		Title       string
+		TrackNumber int
+		DiscNumber  int
+		Duration    time.Duration
+		ISRC        string
+		Explicit    bool
+		Composers   []string
+	}
+
+	ImportRow struct {
+		Line       int
+		Record     ImportRecord
+		Status     RowStatus
+		ResourceID string
+		Error      string
+	}
+
+	ImportJob struct {
+		ID        string
+		Format    string
+		Status    ImportStatus
+		Rows      []ImportRow
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+)
+
+// Key is the natural key the record is resolved by: the artist name, the
+// album title within its artist and the disc/track position within its album.
+func (r ImportRecord) Key() string {
+	switch r.Type {
+	case ArtistRecord:
+		return r.Artist
+	case AlbumRecord:
+		return fmt.Sprintf("%s / %s", r.Artist, r.Album)
+	case TrackRecord:
+		return fmt.Sprintf("%s / %s / %d-%d", r.Artist, r.Album, r.DiscNumber, r.TrackNumber)
+	default:
+		return ""
+	}
+}
+
+func (r *ImportRow) Succeed(resourceID string) {
+	r.Status = RowSucceeded
+	r.ResourceID = resourceID
+	r.Error = ""
+}
+
+func (r *ImportRow) Fail(err error) {
+	r.Status = RowFailed
+	r.Error = err.Error()
+}
+
+func (j ImportJob) Count(status RowStatus) int {
+	var n int
+	for _, row := range j.Rows {
+		if row.Status == status {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,48 @@
+package song
+
+import "time"
+
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+type (
+	Listener struct {
+		ID    string
+		Name  string
+		Email string
+	}
+
+	Listening struct {
+		ID         string
+		ListenerID string
+		Song       Song
+		PlayedAt   time.Time
+	}
+
+	Follow struct {
+		ListenerID string
+		ArtistID   string
+		FollowedAt time.Time
+	}
+
+	Like struct {
+		ListenerID string
+		Song       Song
+		LikedAt    time.Time
+	}
+
+	AlbumRating struct {
+		ListenerID string
+		AlbumID    string
+		Rating     int
+		RatedAt    time.Time
+	}
+
+	Release struct {
+		ListenerID  string
+		Album       Album
+		PublishedAt time.Time
+	}
+)
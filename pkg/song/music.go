@@ -1,15 +1,71 @@
 package song
 
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	DraftStatus     AlbumStatus = "draft"
+	ScheduledStatus AlbumStatus = "scheduled"
+	PublishedStatus AlbumStatus = "published"
+	// LegacyStatus is held by albums created before the lifecycle, which
+	// stay released and keep accepting songs as they did then.
+	LegacyStatus AlbumStatus = ""
+
+	FeaturedRole Role = "featured"
+	GuestRole    Role = "guest"
+	RemixerRole  Role = "remixer"
+	ProducerRole Role = "producer"
+)
+
+var (
+	NotFoundErr        = errors.New("not found")
+	AlreadyExistsErr   = errors.New("already exists")
+	InvalidISRCErr     = errors.New("invalid ISRC")
+	DuplicateISRCErr   = errors.New("duplicate ISRC")
+	AlbumNotDraftErr   = errors.New("album is not a draft")
+	AlbumPublishedErr  = errors.New("album already published")
+	MissingReleaseErr  = errors.New("missing release date")
+	PastReleaseErr     = errors.New("release date is not in the future")
+	SelfMergeErr       = errors.New("cannot merge an artist into itself")
+	VersionConflictErr = errors.New("version conflict")
+
+	isrcPattern = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{3}[0-9]{7}$`)
+)
+
 type (
 	Gender string
 
+	Genre string
+
+	Role string
+
+	AlbumStatus string
+
 	Song struct {
 		ID          string
 		TrackNumber int
 		Title       string
+		Duration    time.Duration
+		ISRC        string
+		Explicit    bool
+		DiscNumber  int
+		Composers   []string
 		Plays       int
+		Likes       int
 		Album       Album
 		Artist      Artist
+		Featuring   []Featuring
+		Version     int
+		UpdatedAt   time.Time
+	}
+
+	Featuring struct {
+		Artist Artist
+		Role   Role
 	}
 
 	Album struct {
@@ -17,13 +73,109 @@ type (
 		Title       string
 		Artist      Artist
 		ReleaseYear int
+		Status      AlbumStatus
+		ReleaseDate time.Time
+		Genres      []Genre
+		Duration    time.Duration
+		Rating      Rating
 		Songs       []Song
+		Version     int
+		UpdatedAt   time.Time
+	}
+
+	Rating struct {
+		Average float64
+		Count   int
 	}
 
 	Artist struct {
-		ID     string
-		Name   string
-		Gender Gender
-		Albums []Album
+		ID        string
+		Name      string
+		Gender    Gender
+		Genres    []Genre
+		Albums    []Album
+		Version   int
+		UpdatedAt time.Time
+	}
+
+	ArtistMerge struct {
+		SourceID string
+		Target   Artist
+		MergedAt time.Time
 	}
 )
+
+// Schedule sets the album to be released at releaseDate, which must come
+// after now: an album meant to be out already is published, not scheduled.
+func (a *Album) Schedule(releaseDate, now time.Time) error {
+	if a.IsReleased() {
+		return AlbumPublishedErr
+	}
+
+	if releaseDate.IsZero() {
+		return MissingReleaseErr
+	}
+
+	if !releaseDate.After(now) {
+		return PastReleaseErr
+	}
+
+	a.Status = ScheduledStatus
+	a.ReleaseDate = releaseDate
+	return nil
+}
+
+func (a Album) AcceptsSongs() bool {
+	return a.Status == DraftStatus || a.Status == LegacyStatus
+}
+
+func (a Album) IsReleased() bool {
+	return a.Status == LegacyStatus || a.Status == PublishedStatus
+}
+
+func (r Role) IsValid() bool {
+	switch r {
+	case FeaturedRole, GuestRole, RemixerRole, ProducerRole:
+		return true
+	default:
+		return false
+	}
+}
+
+func ParseISRC(code string) (string, error) {
+	isrc := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	if !isrcPattern.MatchString(isrc) {
+		return "", InvalidISRCErr
+	}
+
+	return isrc, nil
+}
+
+func NewGenre(name string) Genre {
+	return Genre(strings.ToLower(strings.TrimSpace(name)))
+}
+
+func NewGenres(names []string) []Genre {
+	var genres []Genre
+	seen := make(map[Genre]bool, len(names))
+	for _, name := range names {
+		genre := NewGenre(name)
+		if genre == "" || seen[genre] {
+			continue
+		}
+
+		seen[genre] = true
+		genres = append(genres, genre)
+	}
+
+	return genres
+}
+
+func GenreNames(genres []Genre) []string {
+	var names []string
+	for _, genre := range genres {
+		names = append(names, string(genre))
+	}
+
+	return names
+}
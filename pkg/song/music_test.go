@@ -0,0 +1,76 @@
+package song
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Album_Status(t *testing.T) {
+	tests := []struct {
+		status       AlbumStatus
+		acceptsSongs bool
+		released     bool
+		scheduleErr  error
+	}{
+		{status: DraftStatus, acceptsSongs: true, released: false, scheduleErr: nil},
+		{status: ScheduledStatus, acceptsSongs: false, released: false, scheduleErr: nil},
+		{status: PublishedStatus, acceptsSongs: false, released: true, scheduleErr: AlbumPublishedErr},
+		{status: LegacyStatus, acceptsSongs: true, released: true, scheduleErr: AlbumPublishedErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			album := Album{Status: tt.status}
+
+			if got := album.AcceptsSongs(); got != tt.acceptsSongs {
+				t.Errorf("AcceptsSongs: got = %t, want = %t", got, tt.acceptsSongs)
+			}
+
+			if got := album.IsReleased(); got != tt.released {
+				t.Errorf("IsReleased: got = %t, want = %t", got, tt.released)
+			}
+
+			now := time.Now()
+			if err := album.Schedule(now.Add(time.Hour), now); !errors.Is(err, tt.scheduleErr) {
+				t.Errorf("Schedule: got = %v, want = %v", err, tt.scheduleErr)
+			}
+		})
+	}
+}
+
+func Test_Album_Schedule_Needs_Future_Date(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		releaseDate time.Time
+		want        error
+	}{
+		{name: "future", releaseDate: now.Add(time.Minute), want: nil},
+		{name: "now", releaseDate: now, want: PastReleaseErr},
+		{name: "past", releaseDate: now.AddDate(0, 0, -1), want: PastReleaseErr},
+		{name: "missing", want: MissingReleaseErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			album := Album{Status: DraftStatus}
+
+			if err := album.Schedule(tt.releaseDate, now); !errors.Is(err, tt.want) {
+				t.Errorf("Schedule: got = %v, want = %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_New_Genres(t *testing.T) {
+	// Act
+	got := NewGenres([]string{" Rock", "pop ", "", "ROCK", "Hip Hop", "  "})
+
+	// Assert
+	want := []Genre{"rock", "pop", "hip hop"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewGenres: got = %v, want = %v", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package song
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	SongAlreadyInPlaylistErr = errors.New("song already in playlist")
+	SongNotInPlaylistErr     = errors.New("song not in playlist")
+	InvalidPlaylistOrderErr  = errors.New("invalid playlist order")
+)
+
+type (
+	Playlist struct {
+		ID         string
+		Name       string
+		ListenerID string
+		Songs      []Song
+		Version    int
+	}
+)
+
+func (p *Playlist) Rename(name string) {
+	p.Name = name
+}
+
+func (p *Playlist) AddSong(s Song) error {
+	if p.indexOf(s.ID) >= 0 {
+		return SongAlreadyInPlaylistErr
+	}
+
+	p.Songs = append(p.Songs, s)
+	return nil
+}
+
+func (p *Playlist) RemoveSong(songID string) error {
+	i := p.indexOf(songID)
+	if i < 0 {
+		return SongNotInPlaylistErr
+	}
+
+	p.Songs = append(p.Songs[:i], p.Songs[i+1:]...)
+	return nil
+}
+
+func (p *Playlist) Reorder(songIDs []string) error {
+	if len(songIDs) != len(p.Songs) {
+		return InvalidPlaylistOrderErr
+	}
+
+	songs := make([]Song, len(songIDs), len(songIDs))
+	seen := make(map[string]bool, len(songIDs))
+	for i, id := range songIDs {
+		j := p.indexOf(id)
+		if j < 0 || seen[id] {
+			return InvalidPlaylistOrderErr
+		}
+
+		seen[id] = true
+		songs[i] = p.Songs[j]
+	}
+
+	p.Songs = songs
+	return nil
+}
+
+func (p Playlist) Duration() time.Duration {
+	var total time.Duration
+	for _, s := range p.Songs {
+		total += s.Duration
+	}
+
+	return total
+}
+
+func (p Playlist) indexOf(songID string) int {
+	for i, s := range p.Songs {
+		if s.ID == songID {
+			return i
+		}
+	}
+
+	return -1
+}
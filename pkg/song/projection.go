@@ -0,0 +1,59 @@
+package song
+
+import (
+	"time"
+)
+
+type (
+	// Checkpoint records how far a worker projection has applied the events
+	// published by the command side, and how many are still queued for it.
+	Checkpoint struct {
+		Projection      string
+		LastEventAt     time.Time
+		LastProcessedAt time.Time
+		Processed       int64
+		Pending         int
+		SampledAt       time.Time
+		DrainedAt       time.Time
+		LastError       string
+		LastErrorAt     time.Time
+	}
+
+	// ParkedEvent is an event a projection received before the resource it
+	// depends on, kept aside until that dependency is projected.
+	ParkedEvent struct {
+		ID         string
+		Handler    string
+		Dependency string
+		Body       []byte
+		Headers    map[string]interface{}
+		ParkedAt   time.Time
+	}
+)
+
+// Lag is how far behind the command side the projection may be: nothing
+// when its queue is drained, otherwise the age the oldest pending event may
+// have, which was published after both the last event applied and the last
+// sample that found the queue drained.
+func (c Checkpoint) Lag(now time.Time) time.Duration {
+	since := c.LastEventAt
+	if c.DrainedAt.After(since) {
+		since = c.DrainedAt
+	}
+
+	if c.Pending == 0 || since.IsZero() {
+		return 0
+	}
+
+	return max(now.Sub(since), 0)
+}
+
+// IsStuck reports a projection that has events waiting but applied none of
+// them for longer than after, or whose worker stopped reporting its backlog.
+func (c Checkpoint) IsStuck(now time.Time, after time.Duration) bool {
+	if now.Sub(c.SampledAt) > after {
+		return true
+	}
+
+	return c.Pending > 0 && now.Sub(c.LastProcessedAt) > after
+}
@@ -0,0 +1,44 @@
+package song
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Checkpoint_Lag(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		checkpoint Checkpoint
+		want       time.Duration
+	}{
+		{
+			name:       "drained queue",
+			checkpoint: Checkpoint{LastEventAt: now.Add(-time.Hour)},
+			want:       0,
+		},
+		{
+			name:       "never applied nor drained",
+			checkpoint: Checkpoint{Pending: 3},
+			want:       0,
+		},
+		{
+			name:       "backlog behind last event",
+			checkpoint: Checkpoint{Pending: 3, LastEventAt: now.Add(-time.Minute), DrainedAt: now.Add(-time.Hour)},
+			want:       time.Minute,
+		},
+		{
+			name:       "backlog after idle period",
+			checkpoint: Checkpoint{Pending: 3, LastEventAt: now.Add(-24 * time.Hour), DrainedAt: now.Add(-15 * time.Second)},
+			want:       15 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.checkpoint.Lag(now); got != tt.want {
+				t.Errorf("Lag: got = %v, want = %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package song
+
+import (
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// NormalizeArtistName reduces a name to the form two spellings of the same
+// artist are likely to share: no accents, case, punctuation, spacing or
+// leading or trailing article, and "&" read as "and".
+func NormalizeArtistName(name string) string {
+	// a chained transformer keeps state, so each call builds its own
+	stripMarks := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	name, _, _ = transform.String(stripMarks, name)
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.ReplaceAll(name, "&", " and ")
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "the "), ", the")
+
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// NameSimilarity scores two normalized names between 0 and 1 from their
+// edit distance, 1 meaning identical.
+func NameSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
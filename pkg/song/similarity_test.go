@@ -0,0 +1,50 @@
+package song
+
+import (
+	"sync"
+	"testing"
+)
+
+func Test_Normalize_Artist_Name(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Beyoncé", want: "beyonce"},
+		{name: "  The Beatles ", want: "beatles"},
+		{name: "Beatles, The", want: "beatles"},
+		{name: "Simon & Garfunkel", want: "simonandgarfunkel"},
+		{name: "AC/DC", want: "acdc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeArtistName(tt.name); got != tt.want {
+				t.Errorf("NormalizeArtistName: got = %s, want = %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Normalize_Artist_Name_Concurrently(t *testing.T) {
+	// Arrange
+	var wg sync.WaitGroup
+	got := make([]string, 50)
+
+	// Act
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i] = NormalizeArtistName("Sigur Rós")
+		}(i)
+	}
+	wg.Wait()
+
+	// Assert
+	for i, name := range got {
+		if name != "sigurros" {
+			t.Errorf("name %d: got = %s, want = sigurros", i, name)
+		}
+	}
+}